@@ -0,0 +1,56 @@
+package main
+
+// the first (key . value) pair in alist whose key matches, or nil
+func findPair(key LispObject, alist LispObject, match func(a, b LispObject) bool) *cons {
+	for _, entry := range toSlice(alist) {
+		if pair, ok := entry.(*cons); ok && match(pair.car, key) {
+			return pair
+		}
+	}
+	return nil
+}
+
+// (assoc "b" (quote (("a" . 1) ("b" . 2)))) -> ("b" . 2), comparing with equal?
+func assoc(rawlist []LispObject, env Environment) LispObject {
+	if pair := findPair(rawlist[1].Eval(env), rawlist[2].Eval(env), equalHelper); pair != nil {
+		return pair
+	}
+	return False
+}
+
+// (assq (quote b) (quote ((a . 1) (b . 2)))) -> (b . 2), comparing with eq?
+func assq(rawlist []LispObject, env Environment) LispObject {
+	if pair := findPair(rawlist[1].Eval(env), rawlist[2].Eval(env), eqHelper); pair != nil {
+		return pair
+	}
+	return False
+}
+
+// (acons (quote a) 1 alist) -> ((a . 1) . alist)
+func acons(rawlist []LispObject, env Environment) LispObject {
+	pair := &cons{car: rawlist[1].Eval(env), cdr: rawlist[2].Eval(env)}
+	return &cons{car: pair, cdr: rawlist[3].Eval(env)}
+}
+
+// (alist-get key alist) returns the value for key, or the optional default
+// (() if there isn't one) when key is missing
+func alistGet(rawlist []LispObject, env Environment) LispObject {
+	if pair := findPair(rawlist[1].Eval(env), rawlist[2].Eval(env), equalHelper); pair != nil {
+		return pair.cdr
+	}
+	if len(rawlist) > 3 {
+		return rawlist[3].Eval(env)
+	}
+	return Nil
+}
+
+// earlier entries shadow later ones, as they do for assoc
+func alistToHash(rawlist []LispObject, env Environment) LispObject {
+	h := newHashTable()
+	entries := toSlice(rawlist[1].Eval(env))
+	for i := len(entries) - 1; i >= 0; i-- {
+		pair := checkCons(entries[i])
+		h.Put(pair.car, pair.cdr)
+	}
+	return h
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// a Go channel of lisp values, made by (chan)
+type lispChan struct {
+	v reflect.Value
+}
+
+func (c *lispChan) Eval(env Environment) LispObject {
+	return c
+}
+func (c *lispChan) Print() string {
+	return "<" + c.v.Type().String() + ">"
+}
+
+// a context made by timeout-context, which cancel! can end early
+type lispContext struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (c *lispContext) Eval(env Environment) LispObject {
+	return c
+}
+func (c *lispContext) Print() string {
+	return "<context>"
+}
+
+func checkChan(obj LispObject) *lispChan {
+	c, ok := obj.(*lispChan)
+	if !ok {
+		panic("expected a channel, got " + obj.Print())
+	}
+	return c
+}
+
+func checkContext(obj LispObject) context.Context {
+	c, ok := obj.(*lispContext)
+	if !ok {
+		panic("expected a context, got " + obj.Print())
+	}
+	return c.ctx
+}
+
+// an optional trailing context argument, or the background context
+func optionalContext(rawlist []LispObject, i int, env Environment) context.Context {
+	if len(rawlist) > i {
+		return checkContext(rawlist[i].Eval(env))
+	}
+	return context.Background()
+}
+
+// waits on the channel case, failing if ctx is done first
+func selectOrDone(c reflect.SelectCase, ctx context.Context) (reflect.Value, bool) {
+	cases := []reflect.SelectCase{c, {Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}}
+	chosen, v, ok := reflect.Select(cases)
+	if chosen == 1 {
+		panic(ctx.Err().Error())
+	}
+	return v, ok
+}
+
+// (send! c 1) -> 1, blocking until received. (send! c 1 ctx) gives up when
+// ctx is done
+func send(rawlist []LispObject, env Environment) LispObject {
+	c := checkChan(rawlist[1].Eval(env))
+	val := rawlist[2].Eval(env)
+	out := reflect.SelectCase{Dir: reflect.SelectSend, Chan: c.v, Send: lispToGo(val, c.v.Type().Elem())}
+	selectOrDone(out, optionalContext(rawlist, 3, env))
+	return val
+}
+
+// (recv! c) -> the next value, or the eof object once c is closed and empty.
+// (recv! c ctx) gives up when ctx is done
+func recv(rawlist []LispObject, env Environment) LispObject {
+	c := checkChan(rawlist[1].Eval(env))
+	in := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: c.v}
+	v, ok := selectOrDone(in, optionalContext(rawlist, 2, env))
+	if !ok {
+		return EOF
+	}
+	return goToLisp(v)
+}
+
+// (timeout-context 1500) -> a context done after 1500 milliseconds, like
+// --slow-calls and slow-call-threshold! count. (timeout-context ctx 1500)
+// derives it from ctx instead
+func timeoutContext(rawlist []LispObject, env Environment) LispObject {
+	parent := context.Background()
+	ms := rawlist[1].Eval(env)
+	if len(rawlist) > 2 {
+		parent = checkContext(ms)
+		ms = rawlist[2].Eval(env)
+	}
+	d := time.Duration(float64(toFlonum(ms)) * float64(time.Millisecond))
+	ctx, cancel := context.WithTimeout(parent, d)
+	return &lispContext{ctx: ctx, cancel: cancel}
+}
+
+// (cancel! ctx) ends a context made by timeout-context early
+func cancelContext(rawlist []LispObject, env Environment) LispObject {
+	c, ok := rawlist[1].Eval(env).(*lispContext)
+	if !ok {
+		panic("cancel! needs a context")
+	}
+	c.cancel()
+	return Nil
+}
+
+// (done? ctx) -> #t once ctx is cancelled or timed out
+func isDone(rawlist []LispObject, env Environment) LispObject {
+	return boolToLisp(checkContext(rawlist[1].Eval(env)).Err() != nil)
+}
+
+// (chan) -> an unbuffered channel of lisp values, (chan 10) a buffered one
+func makeChan(rawlist []LispObject, env Environment) LispObject {
+	size := 0
+	if len(rawlist) > 1 {
+		size = int(rawlist[1].Eval(env).(fixnum))
+	}
+	return &lispChan{v: reflect.ValueOf(make(chan LispObject, size))}
+}
+
+// (close! c) -> (), after which recv! drains c and then returns the eof object
+func closeChan(rawlist []LispObject, env Environment) LispObject {
+	c := checkChan(rawlist[1].Eval(env))
+	c.v.Close()
+	return Nil
+}
+
+// waits on several channel operations, running the clause of the one that
+// happens first:
+//
+//	(select ((recv! c) v (use v))
+//	        ((send! out 1) (sent))
+//	        (default (nothing-ready)))
+//
+// v is bound to the received value, or the eof object if c is closed
+func selectForm(rawlist []LispObject, env Environment) LispObject {
+	cases := []reflect.SelectCase{}
+	clauses := [][]LispObject{}
+	for _, obj := range rawlist[1:] {
+		clause := toSlice(obj)
+		if s, ok := clause[0].(*symbol); ok && s.name == "default" {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+			clauses = append(clauses, clause)
+			continue
+		}
+		op := toSlice(clause[0])
+		c := checkChan(op[1].Eval(env))
+		switch op[0].(*symbol).name {
+		case "recv!":
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: c.v})
+		case "send!":
+			val := lispToGo(op[2].Eval(env), c.v.Type().Elem())
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: c.v, Send: val})
+		default:
+			panic("select clauses start with (recv! c), (send! c v) or default")
+		}
+		clauses = append(clauses, clause)
+	}
+	chosen, v, ok := reflect.Select(cases)
+	clause := clauses[chosen]
+	if cases[chosen].Dir != reflect.SelectRecv {
+		return clause[1].Eval(env)
+	}
+	var received LispObject = EOF
+	if ok {
+		received = goToLisp(v)
+	}
+	name := clause[1].(*symbol).name
+	return clause[2].Eval(env.FromParent([]string{name}, []LispObject{received}))
+}
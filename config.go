@@ -0,0 +1,37 @@
+package main
+
+// the intrinsics config files can use: the ones that only compute with their
+// arguments. anything doing io, starting goroutines or changing state outside
+// the file, like print, future, put or prune-env!, is left out. so is eval,
+// which would run code outside this env, and the while and do loops, though
+// a recursive def can still run forever
+var configIntrinsics = []string{
+	"+", "-", "*", "/", ">", ">=", "<", "<=", "=", "compare",
+	"not", "boolean", "and", "or", "if", "cond", "case", "begin",
+	"lambda", "def", "defstruct", "set!", "quote", "let", "let*", "letrec",
+	"quasiquote", "unquote", "unquote-splicing", "apply", "map", "the-environment",
+	"car", "cdr", "cons", "list", "append", "length", "set-car!", "set-cdr!",
+	"eq?", "equal?", "diff", "prewalk", "postwalk", "sexp-select",
+	"delay", "force", "promise?", "stream-cons", "stream-car", "stream-cdr", "stream-take",
+	"assoc", "assq", "acons", "alist-get", "alist->hash",
+	"make-hash", "hash-get", "hash-set!", "hash-del!", "hash-keys", "hash?",
+	"queue", "push-front!", "push-back!", "pop-front!", "pop-back!", "queue-empty?",
+	"error", "raise", "make-error", "error-message", "error-kind", "error-data", "error?",
+	"re-compile", "re-match?", "re-find", "re-find-all", "re-replace",
+	"time->string", "string->time", "time-add", "time-diff", "time-parts", "format-date", "parse-date",
+	"format-number", "decimal", "decimal-round", "decimal?",
+	"nil?", "symbol?", "symbol->string", "string->symbol", "string?", "char?", "boolean?",
+	"keyword?", "char->int", "int->char", "num?", "fixnum?", "integer?", "rational?", "float?",
+	"list?", "pair?", "lambda?", "intrinsic?",
+	"string-length", "substring", "string-ref", "string-upcase", "string-downcase",
+	"string-byte-length", "string-byte-ref", "byte-substring"}
+
+// config files are evaluated with only configIntrinsics, so they can compute
+// values but can't touch files, the terminal or Go functions
+func configEnv() Environment {
+	env := newEnv(len(configIntrinsics))
+	for _, name := range configIntrinsics {
+		env.Put(name, IntrinsicList[name])
+	}
+	return env
+}
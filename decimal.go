@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+)
+
+// a fixed point decimal, val / 10^scale, for money where floats won't do.
+// decimals print and read as #d19.99
+type decimal struct {
+	val   *big.Int
+	scale int
+}
+
+func (d decimal) Eval(env Environment) LispObject {
+	return d
+}
+func (d decimal) Print() string {
+	digits := new(big.Int).Abs(d.val).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if d.val.Sign() < 0 {
+		digits = "-" + digits
+	}
+	return "#d" + digits
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func (d decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.val, pow10(d.scale))
+}
+
+// "19.99" -> 1999 at scale 2
+func parseDecimal(s string) (decimal, bool) {
+	whole, frac, _ := strings.Cut(s, ".")
+	digits := whole + frac
+	if strings.Trim(strings.TrimLeft(digits, "+-"), "0123456789") != "" || strings.ContainsAny(frac, "+-") {
+		return decimal{}, false
+	}
+	val, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return decimal{}, false
+	}
+	return decimal{val: val, scale: len(frac)}, true
+}
+
+// r as a decimal of exactly scale places, if it has one
+func ratToDecimal(r *big.Rat, scale int) (decimal, bool) {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(scale)))
+	if !scaled.IsInt() {
+		return decimal{}, false
+	}
+	return decimal{val: new(big.Int).Set(scaled.Num()), scale: scale}, true
+}
+
+// r rounded to scale places, halves going to the even neighbour so repeated
+// rounding doesn't drift upwards
+func roundHalfEven(r *big.Rat, scale int) decimal {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(scale)))
+	q, m := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	// compare twice the remainder against the denominator to find the half
+	half := new(big.Int).Abs(m)
+	half.Lsh(half, 1)
+	switch c := half.Cmp(scaled.Denom()); {
+	case c > 0, c == 0 && q.Bit(0) == 1:
+		if scaled.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return decimal{val: q, scale: scale}
+}
+
+func decimalScale(obj LispObject) int {
+	if d, ok := obj.(decimal); ok {
+		return d.scale
+	}
+	return 0
+}
+
+// the result of exact arithmetic on a decimal and an integer or decimal stays
+// a decimal when a scale up to the sum of the operands' holds it exactly,
+// which covers add, subtract and multiply. division that doesn't come out
+// even gives a rational, ready for decimal-round
+func decimalResult(a, b LispObject, r *big.Rat) LispObject {
+	if _, ok := a.(ratnum); ok {
+		return normalizeRat(r)
+	}
+	if _, ok := b.(ratnum); ok {
+		return normalizeRat(r)
+	}
+	sa, sb := decimalScale(a), decimalScale(b)
+	for s := max(sa, sb); s <= sa+sb; s++ {
+		if d, ok := ratToDecimal(r, s); ok {
+			return d
+		}
+	}
+	return normalizeRat(r)
+}
+
+// (decimal "19.99") -> #d19.99. (decimal 2/3 2) rounds an exact number to 2
+// places, halves to even
+func makeDecimal(rawlist []LispObject, env Environment) LispObject {
+	arg := rawlist[1].Eval(env)
+	var r *big.Rat
+	if s, ok := arg.(lispString); ok {
+		d, ok := parseDecimal(string(s))
+		if !ok {
+			panic("not a decimal: " + string(s))
+		}
+		if len(rawlist) < 3 {
+			return d
+		}
+		r = d.Rat()
+	} else {
+		r = toRat(arg)
+	}
+	scale := 0
+	if len(rawlist) > 2 {
+		scale = int(rawlist[2].Eval(env).(fixnum))
+	} else if _, ok := arg.(ratnum); ok {
+		panic("decimal needs a number of places to round a fraction to")
+	}
+	return roundHalfEven(r, scale)
+}
+
+// (decimal-round #d2.345 2) -> #d2.34
+func decimalRound(rawlist []LispObject, env Environment) LispObject {
+	r := toRat(rawlist[1].Eval(env))
+	return roundHalfEven(r, int(rawlist[2].Eval(env).(fixnum)))
+}
+
+func isDecimal(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(decimal)
+	return boolToLisp(ok)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// a hash of v that agrees with equal?: equal values always hash the same, so
+// results can key Go maps after a collision check with equalHelper
+func hashValue(v LispObject) uint64 {
+	h := fnv.New64a()
+	hashInto(h.Write, v)
+	return h.Sum64()
+}
+
+// feeds a type tag and then v's contents to write, recursing like equalHelper
+func hashInto(write func([]byte) (int, error), v LispObject) {
+	num := func(tag byte, n uint64) {
+		buf := [9]byte{tag}
+		binary.LittleEndian.PutUint64(buf[1:], n)
+		write(buf[:])
+	}
+	text := func(tag byte, s string) {
+		num(tag, uint64(len(s)))
+		write([]byte(s))
+	}
+	switch val := v.(type) {
+	case *cons:
+		write([]byte{'('})
+		hashInto(write, val.car)
+		hashInto(write, val.cdr)
+	case fixnum:
+		num('i', uint64(val))
+	case flonum:
+		// 0.0 and -0.0 are equal? so they have to hash alike
+		if val == 0 {
+			val = 0
+		}
+		num('f', math.Float64bits(float64(val)))
+	case bignum:
+		text('b', val.val.String())
+	case ratnum:
+		text('r', val.val.String())
+	case decimal:
+		// #d1.50 and #d1.5 are equal? so hash the value, not the digits
+		text('d', val.Rat().String())
+	case *symbol:
+		text('s', val.name)
+	case lispString:
+		text('"', string(val))
+	case keyword:
+		text(':', string(val))
+	case lispChar:
+		num('c', uint64(val))
+	case lispNil:
+		write([]byte{'n'})
+	case lispBool:
+		text('?', val.Print())
+	case lispTime:
+		// times in different zones can be equal, so hash the instant
+		num('t', uint64(val.t.UnixNano()))
+	case *record:
+		text('R', val.typ.name)
+		for _, f := range val.fields {
+			hashInto(write, f)
+		}
+	default:
+		// anything else is only equal? to itself, so two lambdas or hashes
+		// that print the same shouldn't share a bucket
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer {
+			num('p', uint64(rv.Pointer()))
+		} else {
+			text('?', v.Print())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import "fmt"
+
+// a condition: a message, a symbol saying what kind of error it is and
+// whatever data the signaller wants to attach. it's also a Go error, so it can
+// be raised as a panic and handed back to Go callers, and cause keeps the Go
+// error it was made from
+type lispError struct {
+	message string
+	kind    *symbol
+	data    LispObject
+	cause   error
+}
+
+func (e *lispError) Error() string {
+	return e.message
+}
+func (e *lispError) Unwrap() error {
+	return e.cause
+}
+
+// converts an error returned by Go code into a condition of kind go-error
+func fromGoError(err error) *lispError {
+	if e, ok := err.(*lispError); ok {
+		return e
+	}
+	return &lispError{message: err.Error(), kind: intern("go-error"), data: Nil, cause: err}
+}
+
+func (e *lispError) Eval(env Environment) LispObject {
+	return e
+}
+func (e *lispError) Print() string {
+	return "<error " + e.kind.name + ": " + e.message + ">"
+}
+
+func checkError(obj LispObject) *lispError {
+	e, ok := obj.(*lispError)
+	if !ok {
+		panic("expected an error, got " + obj.Print())
+	}
+	return e
+}
+
+// (make-error "no such user" (quote not-found) 42) -> <error not-found: no such user>
+// the kind defaults to error and the data to ()
+func makeError(rawlist []LispObject, env Environment) LispObject {
+	msg, ok := rawlist[1].Eval(env).(lispString)
+	if !ok {
+		panic("make-error needs a message string")
+	}
+	e := &lispError{message: string(msg), kind: intern("error"), data: Nil}
+	if len(rawlist) > 2 {
+		kind, ok := rawlist[2].Eval(env).(*symbol)
+		if !ok {
+			panic("an error kind must be a symbol")
+		}
+		e.kind = kind
+	}
+	if len(rawlist) > 3 {
+		e.data = rawlist[3].Eval(env)
+	}
+	return e
+}
+
+func errorMessage(rawlist []LispObject, env Environment) LispObject {
+	return lispString(checkError(rawlist[1].Eval(env)).message)
+}
+
+func errorKind(rawlist []LispObject, env Environment) LispObject {
+	return checkError(rawlist[1].Eval(env)).kind
+}
+
+func errorData(rawlist []LispObject, env Environment) LispObject {
+	return checkError(rawlist[1].Eval(env)).data
+}
+
+func isError(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(*lispError)
+	return boolToLisp(ok)
+}
+
+// (error "no such user" name) raises an error of kind error whose message is
+// followed by the printed irritants, which are also its data
+func signalError(rawlist []LispObject, env Environment) LispObject {
+	msg, ok := rawlist[1].Eval(env).(lispString)
+	if !ok {
+		panic("error needs a message string")
+	}
+	text := string(msg)
+	irritants := make([]LispObject, len(rawlist)-2)
+	for i, arg := range rawlist[2:] {
+		irritants[i] = arg.Eval(env)
+		text += " " + irritants[i].Print()
+	}
+	panic(&lispError{message: text, kind: intern("error"), data: list(irritants...)})
+}
+
+// (raise (make-error "oops")) panics with the error itself, so Go callers
+// get it back with errors.As
+func raise(rawlist []LispObject, env Environment) LispObject {
+	panic(checkError(rawlist[1].Eval(env)))
+}
+
+// turns a recovered panic into an error, keeping conditions and Go errors
+// intact so errors.Is and errors.As see through them
+func recoveredError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// runs fn, turning a panic anywhere in the evaluator into an error. Go code
+// that evaluates lisp and wants an error back goes through here
+func protect(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// evaluates every form in src, returning the value of the last, or () when
+// there are none
+func evalString(src string, env Environment) (result LispObject, err error) {
+	err = protect(func() {
+		result = Nil
+		for _, form := range ReadAll(src) {
+			result = form.Eval(env)
+		}
+	})
+	return result, err
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// the exercises `exercise run` checks solutions against, named after their
+// files. each starts with an (exercise description skeleton) form, followed
+// by the deftests a solution has to pass. the tests stay hidden from the
+// student
+//
+//go:embed exercises/*.lisp
+var exerciseFiles embed.FS
+
+type exercise struct {
+	name        string
+	description string
+	skeleton    string
+	tests       []LispObject
+}
+
+func parseExercise(name string, forms []LispObject) exercise {
+	var l []LispObject
+	ok := len(forms) > 0
+	if ok {
+		l, ok = listToSlice(forms[0])
+	}
+	if !ok || len(l) != 3 || l[0] != intern("exercise") {
+		panic(name + ": expected (exercise description skeleton) first")
+	}
+	description, ok1 := l[1].(lispString)
+	skeleton, ok2 := l[2].(lispString)
+	if !ok1 || !ok2 {
+		panic(name + ": an exercise's description and skeleton must be strings")
+	}
+	ex := exercise{name: name, description: string(description), skeleton: string(skeleton)}
+	for i, form := range forms[1:] {
+		if c, ok := form.(*cons); !ok || c.car != intern("deftest") {
+			panic(fmt.Sprintf("%v: form %d is not a deftest", name, i+2))
+		}
+		ex.tests = append(ex.tests, form)
+	}
+	return ex
+}
+
+// every embedded exercise, in name order
+func loadExercises() (exercises []exercise, err error) {
+	entries, err := exerciseFiles.ReadDir("exercises")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		src, err := exerciseFiles.ReadFile("exercises/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(entry.Name(), ".lisp")
+		err = protect(func() {
+			exercises = append(exercises, parseExercise(name, ReadAll(string(src))))
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return exercises, nil
+}
+
+func findExercise(name string) (exercise, error) {
+	exercises, err := loadExercises()
+	if err != nil {
+		return exercise{}, err
+	}
+	for _, ex := range exercises {
+		if ex.name == name {
+			return ex, nil
+		}
+	}
+	return exercise{}, fmt.Errorf("no exercise called %v", name)
+}
+
+// loads the solution src into a fresh test environment and runs the
+// exercise's tests against it, returning a message for each one that fails
+func (ex exercise) verify(src string) []string {
+	s := &testSuite{file: ex.name}
+	env := s.env()
+	if _, err := evalString(src, env); err != nil {
+		return []string{"loading the solution failed: " + err.Error()}
+	}
+	for _, form := range ex.tests {
+		form.Eval(env)
+	}
+	return s.run()
+}
+
+// `exercise list` names every exercise, `exercise run name` shows one and
+// `exercise run name file` checks the solution in file
+func runExercise(args []string, out io.Writer) error {
+	if len(args) == 1 && args[0] == "list" {
+		exercises, err := loadExercises()
+		if err != nil {
+			return err
+		}
+		for _, ex := range exercises {
+			fmt.Fprintf(out, "%-10v %v\n", ex.name, ex.description)
+		}
+		return nil
+	}
+	if len(args) < 2 || len(args) > 3 || args[0] != "run" {
+		return fmt.Errorf("usage: exercise list | exercise run name [solution-file]")
+	}
+	ex, err := findExercise(args[1])
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		fmt.Fprintf(out, "%v\n\n%v\n", ex.description, ex.skeleton)
+		return nil
+	}
+	src, err := os.ReadFile(args[2])
+	if err != nil {
+		return err
+	}
+	failures := ex.verify(string(src))
+	for _, failure := range failures {
+		fmt.Fprintln(out, failure)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v: %d of %d tests failed", ex.name, len(failures), len(ex.tests))
+	}
+	fmt.Fprintf(out, "%v: all %d tests pass\n", ex.name, len(ex.tests))
+	return nil
+}
@@ -0,0 +1,13 @@
+(exercise
+  "Define (fizzbuzz n) giving fizz when n is a multiple of 3, buzz when it's a multiple of 5, fizzbuzz when it's both and n otherwise."
+  "(def fizzbuzz (n)
+  n)")
+
+(deftest plain-numbers
+  (assert-equal (fizzbuzz 1) 1)
+  (assert-equal (fizzbuzz 31) 31))
+
+(deftest multiples
+  (assert-equal (fizzbuzz 3) 'fizz)
+  (assert-equal (fizzbuzz 10) 'buzz)
+  (assert-equal (fizzbuzz 30) 'fizzbuzz))
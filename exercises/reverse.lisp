@@ -0,0 +1,12 @@
+(exercise
+  "Define (rev l) giving the elements of the list l in reverse order."
+  "(def rev (l)
+  l)")
+
+(deftest short-lists
+  (assert-equal (rev ()) ())
+  (assert-equal (rev '(1)) '(1)))
+
+(deftest longer-lists
+  (assert-equal (rev '(1 2 3)) '(3 2 1))
+  (assert-equal (rev '((a b) c)) '(c (a b))))
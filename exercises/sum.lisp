@@ -0,0 +1,12 @@
+(exercise
+  "Define (sum l) adding up the numbers in the list l, giving 0 for an empty list."
+  "(def sum (l)
+  0)")
+
+(deftest short-lists
+  (assert-equal (sum ()) 0)
+  (assert-equal (sum '(5)) 5))
+
+(deftest longer-lists
+  (assert-equal (sum '(1 2 3 4)) 10)
+  (assert-equal (sum '(1/2 1/2)) 1))
@@ -0,0 +1,45 @@
+package main
+
+// an optional group of intrinsics. the core language lives in IntrinsicList;
+// anything that touches the terminal or filesystem is an extension so it can
+// be left out of an environment. this is a main package, so the only users are
+// newGlobalEnv and the tests; there's no embedding API to hand them to
+type extension interface {
+	Register(env Environment) error
+}
+
+// an extension that is just a table of intrinsics
+type intrinsicSet map[string]Intrinsic
+
+func (s intrinsicSet) Register(env Environment) error {
+	for name, op := range s {
+		env.Put(name, op)
+	}
+	return nil
+}
+
+var terminalIntrinsics = intrinsicSet{
+	"with-progress":  Intrinsic{op: withProgress},
+	"progress!":      Intrinsic{op: progress},
+	"terminal-width": Intrinsic{op: terminalWidth},
+	"clear-screen":   Intrinsic{op: clearScreen},
+	"move-cursor":    Intrinsic{op: moveCursor},
+	"colored":        Intrinsic{op: colored}}
+
+var fileIntrinsics = intrinsicSet{
+	"load":             Intrinsic{op: load},
+	"open-input-file":  Intrinsic{op: openInputFile},
+	"open-output-file": Intrinsic{op: openOutputFile}}
+
+// the extensions the command line interpreter starts with
+var defaultExtensions = []extension{terminalIntrinsics, fileIntrinsics, stdlibFunctions, schemeCompat{}}
+
+// registers each extension into env, stopping at the first error
+func useExtensions(env Environment, exts ...extension) error {
+	for _, ext := range exts {
+		if err := ext.Register(env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// (format-number 1234567.891 :precision 2) -> "1,234,567.89". :separator and
+// :decimal change the grouping and decimal marks for other locales, so
+// (format-number 1234.5 :precision 2 :separator "." :decimal ",") -> "1.234,50".
+// integers are exact at any precision, and without :precision floats and
+// rationals use as many digits as a float needs
+func formatNumber(rawlist []LispObject, env Environment) LispObject {
+	n := rawlist[1].Eval(env)
+	if !isNumber(n) {
+		panic("format-number needs a number, got " + n.Print())
+	}
+	precision, separator, point := -1, ",", "."
+	opts := rawlist[2:]
+	for i := 0; i < len(opts); i += 2 {
+		name, ok := opts[i].(keyword)
+		if !ok || i+1 == len(opts) {
+			panic("format-number takes :keyword value options")
+		}
+		val := opts[i+1].Eval(env)
+		switch name {
+		case "precision":
+			precision = int(val.(fixnum))
+		case "separator":
+			separator = string(val.(lispString))
+		case "decimal":
+			point = string(val.(lispString))
+		default:
+			panic("unknown format-number option :" + string(name))
+		}
+	}
+	var digits string
+	switch v := n.(type) {
+	case fixnum, bignum:
+		digits = v.Print()
+		if precision > 0 {
+			digits += "." + strings.Repeat("0", precision)
+		}
+	case ratnum:
+		if precision < 0 {
+			digits = strconv.FormatFloat(float64(toFlonum(v)), 'f', -1, 64)
+		} else {
+			digits = v.val.FloatString(precision)
+		}
+	case decimal:
+		if precision >= 0 {
+			v = roundHalfEven(v.Rat(), precision)
+		}
+		digits = strings.TrimPrefix(v.Print(), "#d")
+	default:
+		digits = strconv.FormatFloat(float64(toFlonum(n)), 'f', precision, 64)
+	}
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(digits, ".")
+	var b strings.Builder
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(separator)
+		}
+		b.WriteRune(d)
+	}
+	if hasFrac {
+		b.WriteString(point + frac)
+	}
+	return lispString(sign + b.String())
+}
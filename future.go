@@ -0,0 +1,82 @@
+package main
+
+// an expression being evaluated in its own goroutine. done closes once value
+// or failure is set
+type future struct {
+	done    chan struct{}
+	value   LispObject
+	failure interface{}
+}
+
+func (f *future) Eval(env Environment) LispObject {
+	return f
+}
+func (f *future) Print() string {
+	return "<future>"
+}
+
+// waits for the result, re-raising any panic from the goroutine
+func (f *future) Deref() LispObject {
+	<-f.done
+	if f.failure != nil {
+		panic(f.failure)
+	}
+	return f.value
+}
+
+// copies every binding visible from env into one fresh env, so a goroutine
+// can read it while the caller keeps changing its own
+func snapshotEnv(env Environment) Environment {
+	chain := []*Environment{}
+	for e := &env; e != nil; e = e.Parent {
+		chain = append(chain, e)
+	}
+	snap := newEnv(0)
+	envLock.RLock()
+	defer envLock.RUnlock()
+	for i := len(chain) - 1; i >= 0; i-- {
+		for name, val := range chain[i].Fields {
+			snap.Fields[name] = val
+		}
+	}
+	return snap
+}
+
+// (future (slow-thing)) -> <future> right away. the expression runs in a
+// snapshot of env so it doesn't race with the caller
+func makeFuture(rawlist []LispObject, env Environment) LispObject {
+	f := &future{done: make(chan struct{})}
+	expr := rawlist[1]
+	child := snapshotEnv(env)
+	go func() {
+		defer close(f.done)
+		defer func() {
+			f.failure = recover()
+		}()
+		f.value = expr.Eval(child)
+	}()
+	return f
+}
+
+func checkFuture(obj LispObject) *future {
+	f, ok := obj.(*future)
+	if !ok {
+		panic("expected a future, got " + obj.Print())
+	}
+	return f
+}
+
+// (deref (future (+ 1 2))) -> 3, blocking until it's ready
+func deref(rawlist []LispObject, env Environment) LispObject {
+	return checkFuture(rawlist[1].Eval(env)).Deref()
+}
+
+// (realized? f) -> #t once f has finished, without blocking
+func isRealized(rawlist []LispObject, env Environment) LispObject {
+	select {
+	case <-checkFuture(rawlist[1].Eval(env)).done:
+		return True
+	default:
+		return False
+	}
+}
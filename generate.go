@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// an extension for programs that write text files. emit and emit-line write
+// to the current output, which with-output-file redirects to a file for the
+// duration of its body
+type textGenerator struct {
+	dir string
+	out *port
+}
+
+// a generator writing to out until a with-output-file, whose paths are
+// relative to dir
+func newTextGenerator(out io.Writer, dir string) *textGenerator {
+	return &textGenerator{dir: dir, out: newOutputPort("output", out)}
+}
+
+func (g *textGenerator) Register(env Environment) error {
+	env.Put("emit", Intrinsic{op: g.emit})
+	env.Put("emit-line", Intrinsic{op: g.emitLine})
+	env.Put("with-output-file", Intrinsic{op: g.withOutputFile})
+	return nil
+}
+
+// evaluates the generator program src in a global env with g registered
+func (g *textGenerator) Run(src string) error {
+	env := newGlobalEnv()
+	if err := g.Register(env); err != nil {
+		return err
+	}
+	return protect(func() { loadSource(src, env) })
+}
+
+// strings are written as they are and anything else in its printed form
+func (g *textGenerator) write(rawlist []LispObject, env Environment) {
+	for _, arg := range rawlist[1:] {
+		text := ""
+		switch v := arg.Eval(env).(type) {
+		case lispString:
+			text = string(v)
+		case lispChar:
+			text = string(rune(v))
+		default:
+			text = v.Print()
+		}
+		if _, err := io.WriteString(g.out.out, text); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// (emit "package " name) -> ()
+func (g *textGenerator) emit(rawlist []LispObject, env Environment) LispObject {
+	g.write(rawlist, env)
+	return Nil
+}
+
+// like emit, then a newline
+func (g *textGenerator) emitLine(rawlist []LispObject, env Environment) LispObject {
+	g.write(rawlist, env)
+	if _, err := io.WriteString(g.out.out, "\n"); err != nil {
+		panic(err.Error())
+	}
+	return Nil
+}
+
+// (with-output-file "gen/types.go" body...) sends body's emits to the file,
+// creating its directory, and returns the path
+func (g *textGenerator) withOutputFile(rawlist []LispObject, env Environment) LispObject {
+	name := rawlist[1].Eval(env).(lispString)
+	path := filepath.Join(g.dir, string(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		panic(err.Error())
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err.Error())
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	outer := g.out
+	g.out = newOutputPort(path, w)
+	defer func() { g.out = outer }()
+	body(rawlist[2:]).Eval(env)
+	if err := w.Flush(); err != nil {
+		panic(err.Error())
+	}
+	return name
+}
@@ -0,0 +1,117 @@
+package main
+
+import "runtime"
+
+// what the body of a generator hands back to next: a yielded value, or the
+// end of the body and the panic that ended it, if any
+type genResult struct {
+	value   LispObject
+	done    bool
+	failure interface{}
+}
+
+// the state shared between next and the goroutine running the body. they
+// take turns, so only one of them touches the environment at a time
+type genState struct {
+	body      LispObject
+	env       Environment
+	resume    chan struct{}
+	results   chan genResult
+	started   bool
+	finished  bool
+	abandoned bool
+}
+
+// the value lisp code holds. once next has started the body, its goroutine
+// stays parked in yield until the body finishes or close-generator! stops
+// it, so a generator dropped part way through pins that goroutine
+type generator struct {
+	*genState
+}
+
+func (g *generator) Eval(env Environment) LispObject {
+	return g
+}
+func (g *generator) Print() string {
+	return "<generator>"
+}
+
+// (generator (yield 1) (yield 2)) -> <generator>. the body doesn't start
+// until the first next, and runs with yield bound to pause it
+func mkGenerator(rawlist []LispObject, env Environment) LispObject {
+	s := &genState{body: body(rawlist[1:]), resume: make(chan struct{}), results: make(chan genResult)}
+	s.env = env.FromParent([]string{"yield"}, []LispObject{Intrinsic{op: s.yield}})
+	return &generator{s}
+}
+
+func (s *genState) run() {
+	result := genResult{done: true}
+	defer func() {
+		if r := recover(); r != nil {
+			result.failure = r
+		}
+		if !s.abandoned {
+			s.results <- result
+		}
+	}()
+	s.body.Eval(s.env)
+}
+
+// (yield x) hands x to the next that's waiting and parks the body until the
+// following one
+func (s *genState) yield(rawlist []LispObject, env Environment) LispObject {
+	s.results <- genResult{value: rawlist[1].Eval(env)}
+	if _, ok := <-s.resume; !ok {
+		s.abandoned = true
+		runtime.Goexit()
+	}
+	return Nil
+}
+
+// (next g) -> the next value g yields, or the eof object once its body has
+// finished. an error in the body is raised by the next that runs into it
+func next(rawlist []LispObject, env Environment) LispObject {
+	g := checkGenerator(rawlist[0], rawlist[1].Eval(env))
+	if g.finished {
+		return EOF
+	}
+	if g.started {
+		g.resume <- struct{}{}
+	} else {
+		g.started = true
+		go g.run()
+	}
+	r := <-g.results
+	if r.done {
+		g.finished = true
+		if r.failure != nil {
+			panic(r.failure)
+		}
+		return EOF
+	}
+	return r.value
+}
+
+// (close-generator! g) stops g's body where it's parked, letting its
+// goroutine exit. next gives the eof object from then on
+func closeGenerator(rawlist []LispObject, env Environment) LispObject {
+	g := checkGenerator(rawlist[0], rawlist[1].Eval(env))
+	if g.started && !g.finished {
+		close(g.resume)
+	}
+	g.finished = true
+	return Nil
+}
+
+func checkGenerator(head, obj LispObject) *generator {
+	g, ok := obj.(*generator)
+	if !ok {
+		panic(head.Print() + " needs a generator, got " + obj.Print())
+	}
+	return g
+}
+
+func isGenerator(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(*generator)
+	return boolToLisp(ok)
+}
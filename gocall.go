@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Go functions callable from lisp through go-call, keyed by the name lisp
+// code uses for them. only registered functions are reachable
+type goFunctionSet map[string]interface{}
+
+var (
+	goFunctions     = map[string]reflect.Value{}
+	goFunctionsLock sync.RWMutex
+)
+
+func (fns goFunctionSet) Register(env Environment) error {
+	goFunctionsLock.Lock()
+	defer goFunctionsLock.Unlock()
+	for name, fn := range fns {
+		v := reflect.ValueOf(fn)
+		if v.Kind() != reflect.Func {
+			return fmt.Errorf("go function %v is a %T, not a function", name, fn)
+		}
+		goFunctions[name] = v
+	}
+	env.Put("go-call", Intrinsic{op: goCall})
+	return nil
+}
+
+// the functions go-call can reach. there's no embedding API to register more,
+// so this list is the whole whitelist
+var stdlibFunctions = goFunctionSet{
+	"strings.ToUpper":   strings.ToUpper,
+	"strings.ToLower":   strings.ToLower,
+	"strings.TrimSpace": strings.TrimSpace,
+	"strings.Contains":  strings.Contains,
+	"strings.Repeat":    strings.Repeat,
+	"strings.Split":     strings.Split,
+	"strings.Join":      strings.Join,
+	"strconv.Quote":     strconv.Quote,
+	"strconv.Atoi":      strconv.Atoi,
+	"math.Sqrt":         math.Sqrt,
+	"math.Pow":          math.Pow}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// (go-call "strings.ToUpper" "abc") -> "ABC"
+func goCall(rawlist []LispObject, env Environment) LispObject {
+	name, ok := rawlist[1].Eval(env).(lispString)
+	if !ok {
+		panic("go-call needs a function name string")
+	}
+	goFunctionsLock.RLock()
+	fn, ok := goFunctions[string(name)]
+	goFunctionsLock.RUnlock()
+	if !ok {
+		panic("no go function registered as " + string(name))
+	}
+	args := make([]LispObject, len(rawlist)-2)
+	for i, arg := range rawlist[2:] {
+		args[i] = arg.Eval(env)
+	}
+	return callGo(string(name), fn, args)
+}
+
+// calls fn with already evaluated args. a trailing error result is raised as
+// a go-error condition when set and dropped otherwise; several results come
+// back as a list
+func callGo(name string, fn reflect.Value, args []LispObject) LispObject {
+	ft := fn.Type()
+	if len(args) < ft.NumIn()-1 || (!ft.IsVariadic() && len(args) != ft.NumIn()) {
+		panic(fmt.Sprintf("%v takes %d arguments, got %d", name, ft.NumIn(), len(args)))
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		t := ft.In(min(i, ft.NumIn()-1))
+		if ft.IsVariadic() && i >= ft.NumIn()-1 {
+			t = t.Elem()
+		}
+		in[i] = lispToGo(arg, t)
+	}
+	out := fn.Call(in)
+	if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+		if !out[n-1].IsNil() {
+			panic(fromGoError(out[n-1].Interface().(error)))
+		}
+		out = out[:n-1]
+	}
+	switch len(out) {
+	case 0:
+		return Nil
+	case 1:
+		return goToLisp(out[0])
+	}
+	results := make([]LispObject, len(out))
+	for i, v := range out {
+		results[i] = goToLisp(v)
+	}
+	return list(results...)
+}
+
+// converts obj to a Go value of type t
+func lispToGo(obj LispObject, t reflect.Type) reflect.Value {
+	switch t.Kind() {
+	case reflect.String:
+		if s, ok := obj.(lispString); ok {
+			return reflect.ValueOf(string(s)).Convert(t)
+		}
+	case reflect.Bool:
+		return reflect.ValueOf(lispToBool(obj)).Convert(t)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch n := obj.(type) {
+		case fixnum:
+			return reflect.ValueOf(int(n)).Convert(t)
+		case lispChar:
+			return reflect.ValueOf(rune(n)).Convert(t)
+		}
+	case reflect.Float32, reflect.Float64:
+		if isNumber(obj) {
+			return reflect.ValueOf(float64(toFlonum(obj))).Convert(t)
+		}
+	case reflect.Slice:
+		items, ok := listToSlice(obj)
+		if !ok {
+			break
+		}
+		s := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			s.Index(i).Set(lispToGo(item, t.Elem()))
+		}
+		return s
+	case reflect.Interface:
+		if reflect.TypeOf(obj).Implements(t) {
+			return reflect.ValueOf(obj)
+		}
+	}
+	panic(fmt.Sprintf("cannot pass %v to go as a %v", obj.Print(), t))
+}
+
+// converts a Go value back into lisp
+func goToLisp(v reflect.Value) LispObject {
+	if !v.IsValid() {
+		return Nil
+	}
+	if v.CanInterface() {
+		if obj, ok := v.Interface().(LispObject); ok {
+			return obj
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return lispString(v.String())
+	case reflect.Bool:
+		return boolToLisp(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fixnum(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fixnum(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return flonum(v.Float())
+	case reflect.Slice, reflect.Array:
+		items := make([]LispObject, v.Len())
+		for i := range items {
+			items[i] = goToLisp(v.Index(i))
+		}
+		return list(items...)
+	case reflect.Map:
+		h := newHashTable()
+		iter := v.MapRange()
+		for iter.Next() {
+			h.Put(goToLisp(iter.Key()), goToLisp(iter.Value()))
+		}
+		return h
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return Nil
+		}
+		return goToLisp(v.Elem())
+	}
+	panic("cannot convert go " + v.Type().String() + " to lisp")
+}
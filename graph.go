@@ -0,0 +1,311 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+type edge struct {
+	from string
+	to   string
+}
+
+// renders edges as a Graphviz digraph
+func dotGraph(edges []edge) string {
+	buf := "digraph {\n"
+	for _, e := range edges {
+		buf += "  " + strconv.Quote(e.from) + " -> " + strconv.Quote(e.to) + ";\n"
+	}
+	return buf + "}\n"
+}
+
+// strings label nodes with their contents, anything else by how it prints
+func nodeName(obj LispObject) string {
+	if s, ok := obj.(lispString); ok {
+		return string(s)
+	}
+	return obj.Print()
+}
+
+// (dot-graph (quote ((a b) (b c)))) -> "digraph {\n  \"a\" -> \"b\";\n ..."
+// each edge can be a (from to) list or a (from . to) pair
+func mkDotGraph(rawlist []LispObject, env Environment) LispObject {
+	edges := []edge{}
+	for _, obj := range toSlice(rawlist[1].Eval(env)) {
+		pair := checkCons(obj)
+		to := pair.cdr
+		if next, ok := to.(*cons); ok {
+			to = next.car
+		}
+		edges = append(edges, edge{from: nodeName(pair.car), to: nodeName(to)})
+	}
+	return lispString(dotGraph(edges))
+}
+
+// the names called from head position anywhere inside form. quoted data isn't
+// code, apart from the unquoted parts of quasiquotes, and calls to names a
+// let binds, including a named let's loop, a lambda's or def's parameters and
+// a do loop's variables are local so they're skipped
+func calledNames(form LispObject, found map[string]bool) {
+	l, ok := nonEmptyList(form)
+	if !ok {
+		return
+	}
+	switch l[0] {
+	case intern("quote"):
+		return
+	case intern("generator"):
+		// yield is bound inside the body
+		inner := map[string]bool{}
+		for _, child := range l[1:] {
+			calledNames(child, inner)
+		}
+		delete(inner, "yield")
+		for name := range inner {
+			found[name] = true
+		}
+		return
+	case intern("quasiquote"):
+		for _, child := range l[1:] {
+			unquotedCalls(child, found)
+		}
+		return
+	case intern("let"), intern("let*"), intern("letrec"):
+		if len(l) < 3 {
+			break
+		}
+		local := map[string]bool{}
+		rest := l[1:]
+		if name, ok := l[1].(*symbol); ok {
+			local[name.name] = true
+			rest = l[2:]
+		}
+		if bindings, ok := listToSlice(rest[0]); ok {
+			vals := []LispObject{}
+			for _, binding := range bindings {
+				if b, ok := nonEmptyList(binding); ok {
+					if name, ok := b[0].(*symbol); ok {
+						local[name.name] = true
+					}
+					vals = append(vals, b[1:]...)
+				}
+			}
+			localCalls(append(vals, rest[1:]...), local, found)
+			return
+		}
+	case intern("lambda"):
+		if len(l) > 1 {
+			localCalls(l[2:], paramNames(l[1]), found)
+			return
+		}
+	case intern("def"):
+		if len(l) > 2 {
+			localCalls(l[3:], paramNames(l[2]), found)
+			return
+		}
+	case intern("do"):
+		// (do ((var init step) ...) (test result ...) body ...)
+		if len(l) < 3 {
+			break
+		}
+		vars, ok1 := listToSlice(l[1])
+		clause, ok2 := listToSlice(l[2])
+		if ok1 && ok2 {
+			local := map[string]bool{}
+			inner := append(append([]LispObject{}, clause...), l[3:]...)
+			for _, v := range vars {
+				if spec, ok := nonEmptyList(v); ok {
+					if name, ok := spec[0].(*symbol); ok {
+						local[name.name] = true
+					}
+					if len(spec) > 1 {
+						calledNames(spec[1], found)
+					}
+					inner = append(inner, spec[2:]...)
+				}
+			}
+			localCalls(inner, local, found)
+			return
+		}
+	}
+	if name, ok := l[0].(*symbol); ok {
+		found[name.name] = true
+	}
+	for _, child := range l {
+		calledNames(child, found)
+	}
+}
+
+// adds the names called in forms to found, apart from the local ones
+func localCalls(forms []LispObject, local map[string]bool, found map[string]bool) {
+	inner := map[string]bool{}
+	for _, form := range forms {
+		calledNames(form, inner)
+	}
+	for name := range inner {
+		if !local[name] {
+			found[name] = true
+		}
+	}
+}
+
+// the names a parameter list binds: plain and rest parameters, a dotted
+// tail, and the name of each (name default) &key parameter
+func paramNames(params LispObject) map[string]bool {
+	names := map[string]bool{}
+	for {
+		switch p := params.(type) {
+		case *symbol:
+			names[p.name] = true
+			return names
+		case *cons:
+			switch param := p.car.(type) {
+			case *symbol:
+				names[param.name] = true
+			case *cons:
+				if name, ok := param.car.(*symbol); ok {
+					names[name.name] = true
+				}
+			}
+			params = p.cdr
+		default:
+			return names
+		}
+	}
+}
+
+// the calls in the unquoted parts of a quasiquote template
+func unquotedCalls(form LispObject, found map[string]bool) {
+	l, ok := nonEmptyList(form)
+	if !ok {
+		return
+	}
+	if l[0] == intern("unquote") || l[0] == intern("unquote-splicing") {
+		for _, child := range l[1:] {
+			calledNames(child, found)
+		}
+		return
+	}
+	for _, child := range l {
+		unquotedCalls(child, found)
+	}
+}
+
+func sortedNames(found map[string]bool) []string {
+	names := []string{}
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func namesToList(names []string) LispObject {
+	syms := []LispObject{}
+	for _, name := range names {
+		syms = append(syms, intern(name))
+	}
+	return list(syms...)
+}
+
+// every name bound to a lambda in env or its parents
+func definedFunctions(env *Environment) map[string]lambda {
+	fns := map[string]lambda{}
+	envLock.RLock()
+	defer envLock.RUnlock()
+	for e := env; e != nil; e = e.Parent {
+		for name, val := range e.Fields {
+			if fn, ok := val.(lambda); ok {
+				if _, shadowed := fns[name]; !shadowed {
+					fns[name] = fn
+				}
+			}
+		}
+	}
+	return fns
+}
+
+// (uses (quote f)) lists the defined functions that f calls
+func uses(rawlist []LispObject, env Environment) LispObject {
+	name := rawlist[1].Eval(env).(*symbol)
+	fn, ok := env.Get(name.name).(lambda)
+	if !ok {
+		panic(name.name + " is not a defined function")
+	}
+	found := map[string]bool{}
+	calledNames(fn.fn, found)
+	fns := definedFunctions(&env)
+	for callee := range found {
+		if _, ok := fns[callee]; !ok {
+			delete(found, callee)
+		}
+	}
+	return namesToList(sortedNames(found))
+}
+
+// (used-by (quote f)) lists the defined functions that call f
+func usedBy(rawlist []LispObject, env Environment) LispObject {
+	name := rawlist[1].Eval(env).(*symbol).name
+	callers := map[string]bool{}
+	for caller, fn := range definedFunctions(&env) {
+		found := map[string]bool{}
+		calledNames(fn.fn, found)
+		if found[name] {
+			callers[caller] = true
+		}
+	}
+	return namesToList(sortedNames(callers))
+}
+
+// the names fn calls that aren't bound in env or to one of its arguments
+func undefinedCalls(fn lambda, env *Environment) []string {
+	found := map[string]bool{}
+	calledNames(fn.fn, found)
+	for _, arg := range fn.arglist {
+		delete(found, arg)
+	}
+	delete(found, fn.rest)
+	for _, k := range fn.keys {
+		delete(found, k.name)
+	}
+	for name := range found {
+		if _, bound := env.Get(name).(lispNil); !bound {
+			delete(found, name)
+		}
+	}
+	return sortedNames(found)
+}
+
+// the static call graph between the functions defined by (def ...) forms, in
+// definition order. calls to intrinsics and undefined names are left out
+func callGraph(forms []LispObject) []edge {
+	names := []string{}
+	bodies := map[string]LispObject{}
+	for _, form := range forms {
+		if !isDefinition(form) {
+			continue
+		}
+		def := toSlice(form)
+		name := def[1].(*symbol).name
+		if _, ok := bodies[name]; !ok {
+			names = append(names, name)
+		}
+		bodies[name] = list(def[3:]...)
+	}
+	edges := []edge{}
+	for _, name := range names {
+		found := map[string]bool{}
+		calledNames(bodies[name], found)
+		called := []string{}
+		for callee := range found {
+			if _, ok := bodies[callee]; ok {
+				called = append(called, callee)
+			}
+		}
+		sort.Strings(called)
+		for _, callee := range called {
+			edges = append(edges, edge{from: name, to: callee})
+		}
+	}
+	return edges
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+type hashEntry struct {
+	key LispObject
+	val LispObject
+}
+
+// keys are bucketed by hashValue and then compared with equal?, so any value
+// that equal? understands can be used as a key
+type hashTable struct {
+	buckets map[uint64][]hashEntry
+	size    int
+}
+
+func newHashTable() *hashTable {
+	return &hashTable{buckets: make(map[uint64][]hashEntry)}
+}
+
+func (h *hashTable) Eval(env Environment) LispObject {
+	return h
+}
+func (h *hashTable) Print() string {
+	return "<hash " + strconv.Itoa(h.size) + ">"
+}
+
+func (h *hashTable) Get(key LispObject) (LispObject, bool) {
+	for _, e := range h.buckets[hashValue(key)] {
+		if equalHelper(e.key, key) {
+			return e.val, true
+		}
+	}
+	return nil, false
+}
+
+func (h *hashTable) Put(key LispObject, val LispObject) {
+	sum := hashValue(key)
+	bucket := h.buckets[sum]
+	for i, e := range bucket {
+		if equalHelper(e.key, key) {
+			bucket[i].val = val
+			return
+		}
+	}
+	h.buckets[sum] = append(bucket, hashEntry{key: key, val: val})
+	h.size++
+}
+
+func (h *hashTable) Delete(key LispObject) {
+	sum := hashValue(key)
+	bucket := h.buckets[sum]
+	for i, e := range bucket {
+		if equalHelper(e.key, key) {
+			h.buckets[sum] = append(bucket[:i:i], bucket[i+1:]...)
+			if len(h.buckets[sum]) == 0 {
+				delete(h.buckets, sum)
+			}
+			h.size--
+			return
+		}
+	}
+}
+
+// keys ordered by their printed form so results don't depend on map order
+func (h *hashTable) Keys() []LispObject {
+	keys := []LispObject{}
+	for _, bucket := range h.buckets {
+		for _, e := range bucket {
+			keys = append(keys, e.key)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].Print() < keys[j].Print()
+	})
+	return keys
+}
+
+func checkHash(obj LispObject) *hashTable {
+	h, ok := obj.(*hashTable)
+	if !ok {
+		panic("expected a hash, got " + obj.Print())
+	}
+	return h
+}
+
+// (make-hash) -> <hash 0>
+func makeHash(rawlist []LispObject, env Environment) LispObject {
+	return newHashTable()
+}
+
+// (hash-get h key) returns () for missing keys unless a default is given
+func hashGet(rawlist []LispObject, env Environment) LispObject {
+	h := checkHash(rawlist[1].Eval(env))
+	if val, ok := h.Get(rawlist[2].Eval(env)); ok {
+		return val
+	}
+	if len(rawlist) > 3 {
+		return rawlist[3].Eval(env)
+	}
+	return Nil
+}
+
+func hashSet(rawlist []LispObject, env Environment) LispObject {
+	h := checkHash(rawlist[1].Eval(env))
+	val := rawlist[3].Eval(env)
+	h.Put(rawlist[2].Eval(env), val)
+	return val
+}
+
+func hashDel(rawlist []LispObject, env Environment) LispObject {
+	h := checkHash(rawlist[1].Eval(env))
+	h.Delete(rawlist[2].Eval(env))
+	return Nil
+}
+
+func hashKeys(rawlist []LispObject, env Environment) LispObject {
+	return list(checkHash(rawlist[1].Eval(env)).Keys()...)
+}
+
+func isHash(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(*hashTable)
+	return boolToLisp(ok)
+}
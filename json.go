@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// renders v as JSON, the way --config prints it. lists become arrays, hash
+// tables objects when every key is a distinct string, symbol or keyword name
+// and arrays of [key, value] pairs otherwise, and records objects of their
+// fields. symbols and keywords are written as plain strings. output is
+// stable: hash keys are sorted and record fields keep their order
+func marshalJSON(v LispObject) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+// the object key for k, or false if k can't be one
+func jsonKey(k LispObject) (string, bool) {
+	switch key := k.(type) {
+	case lispString:
+		return string(key), true
+	case *symbol:
+		return key.name, true
+	case keyword:
+		return string(key), true
+	}
+	return "", false
+}
+
+func writeJSON(buf *bytes.Buffer, v LispObject) error {
+	switch val := v.(type) {
+	case lispNil:
+		buf.WriteString("[]")
+	case *cons:
+		items, ok := listToSlice(val)
+		if !ok {
+			return fmt.Errorf("can't write improper list %v as JSON", val.Print())
+		}
+		buf.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case lispBool:
+		buf.WriteString(strconv.FormatBool(bool(val)))
+	case fixnum:
+		buf.WriteString(strconv.Itoa(int(val)))
+	case bignum:
+		buf.WriteString(val.val.String())
+	case decimal:
+		// every digit is written, so 19.90 stays 19.90
+		buf.WriteString(strings.TrimPrefix(val.Print(), "#d"))
+	case ratnum:
+		// written as a string so the exact value survives
+		writeJSONString(buf, val.val.RatString())
+	case flonum:
+		f := float64(val)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("can't write %v as JSON", val.Print())
+		}
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case lispString:
+		writeJSONString(buf, string(val))
+	case lispChar:
+		writeJSONString(buf, string(rune(val)))
+	case *symbol:
+		writeJSONString(buf, val.name)
+	case keyword:
+		writeJSONString(buf, string(val))
+	case lispTime:
+		writeJSONString(buf, val.t.Format(time.RFC3339Nano))
+	case *hashTable:
+		return writeJSONHash(buf, val)
+	case *record:
+		buf.WriteByte('{')
+		for i, name := range val.typ.fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, name)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, val.fields[i]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("can't write %v as JSON", v.Print())
+	}
+	return nil
+}
+
+func writeJSONHash(buf *bytes.Buffer, h *hashTable) error {
+	keys := h.Keys()
+	asObject := true
+	seen := map[string]bool{}
+	for _, k := range keys {
+		name, ok := jsonKey(k)
+		if !ok || seen[name] {
+			asObject = false
+		}
+		seen[name] = true
+	}
+	start, end := byte('['), byte(']')
+	if asObject {
+		start, end = '{', '}'
+	}
+	buf.WriteByte(start)
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		val, _ := h.Get(k)
+		if asObject {
+			name, _ := jsonKey(k)
+			writeJSONString(buf, name)
+			buf.WriteByte(':')
+		} else {
+			buf.WriteByte('[')
+			if err := writeJSON(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(',')
+		}
+		if err := writeJSON(buf, val); err != nil {
+			return err
+		}
+		if !asObject {
+			buf.WriteByte(']')
+		}
+	}
+	buf.WriteByte(end)
+	return nil
+}
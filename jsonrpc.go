@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// one request per JSON value on the input stream, as sent by editor and
+// notebook integrations
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// error codes from the JSON-RPC 2.0 spec, plus one for lisp errors
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcEvalError      = -32000
+)
+
+type evalParams struct {
+	Code string `json:"code"`
+}
+
+type completeParams struct {
+	Prefix string `json:"prefix"`
+}
+
+type docParams struct {
+	Name string `json:"name"`
+}
+
+type docResult struct {
+	Name string   `json:"name"`
+	Kind string   `json:"kind"`
+	Args []string `json:"args,omitempty"`
+}
+
+// the names bound in env and its parents that start with prefix, sorted
+func completions(prefix string, env *Environment) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	envLock.RLock()
+	defer envLock.RUnlock()
+	for e := env; e != nil; e = e.Parent {
+		for name := range e.Fields {
+			if strings.HasPrefix(name, prefix) && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func handleRPC(req rpcRequest, env Environment) (interface{}, *rpcError) {
+	switch req.Method {
+	case "eval":
+		var params evalParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		result, err := evalString(params.Code, env)
+		if err != nil {
+			return nil, &rpcError{Code: rpcEvalError, Message: err.Error()}
+		}
+		return map[string]string{"value": result.Print()}, nil
+	case "complete":
+		var params completeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		return completions(params.Prefix, &env), nil
+	case "doc":
+		var params docParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		doc := docResult{Name: params.Name}
+		switch v := env.Get(params.Name).(type) {
+		case lambda:
+			doc.Kind = "lambda"
+			doc.Args = append([]string{}, v.arglist...)
+			if v.rest != "" {
+				doc.Args = append(doc.Args, "&rest", v.rest)
+			}
+			if v.keys != nil {
+				doc.Args = append(doc.Args, "&key")
+				for _, k := range v.keys {
+					doc.Args = append(doc.Args, k.name)
+				}
+			}
+		case Intrinsic:
+			doc.Kind = "intrinsic"
+		case lispNil:
+			return nil, &rpcError{Code: rpcInvalidParams, Message: params.Name + " is not bound"}
+		default:
+			doc.Kind = "value"
+		}
+		return doc, nil
+	case "interrupt":
+		// requests are read one at a time, after the last has finished, so
+		// there's never a running eval to interrupt
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "interrupt is not supported"}
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method " + req.Method}
+}
+
+// reads requests from in until it's closed, writing a response to out for each
+// one that has an id. whatever the code being evaluated prints goes to logs
+// instead, so it can't corrupt the responses
+func serveJSONRPC(in io.Reader, out, logs io.Writer, env Environment) {
+	oldPrint, oldTerm, oldStdout := printOut, termOut, stdoutPort.out
+	printOut, termOut, stdoutPort.out = logs, logs, logs
+	defer func() { printOut, termOut, stdoutPort.out = oldPrint, oldTerm, oldStdout }()
+	decoder := json.NewDecoder(in)
+	encoder := json.NewEncoder(out)
+	for {
+		var req rpcRequest
+		if err := decoder.Decode(&req); err != nil {
+			if err != io.EOF {
+				encoder.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+					Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			}
+			return
+		}
+		result, rpcErr := handleRPC(req, env)
+		if len(req.ID) == 0 {
+			continue
+		}
+		encoder.Encode(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+// a delayed expression, evaluated at most once in the environment it was
+// created in
+type promise struct {
+	expr   LispObject
+	env    Environment
+	forced bool
+	value  LispObject
+}
+
+func (p *promise) Eval(env Environment) LispObject {
+	return p
+}
+func (p *promise) Print() string {
+	return "<promise>"
+}
+
+func (p *promise) Force() LispObject {
+	if !p.forced {
+		p.value = p.expr.Eval(p.env)
+		p.forced = true
+		p.expr = nil
+	}
+	return p.value
+}
+
+// (delay (+ 1 2)) -> <promise>
+func delay(rawlist []LispObject, env Environment) LispObject {
+	return &promise{expr: rawlist[1], env: env}
+}
+
+// (force (delay (+ 1 2))) -> 3. anything that isn't a promise is returned as is
+func force(rawlist []LispObject, env Environment) LispObject {
+	val := rawlist[1].Eval(env)
+	if p, ok := val.(*promise); ok {
+		return p.Force()
+	}
+	return val
+}
+
+func isPromise(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(*promise)
+	return boolToLisp(ok)
+}
+
+// (stream-cons 1 rest) is a pair whose cdr is only evaluated when asked for
+func streamCons(rawlist []LispObject, env Environment) LispObject {
+	return &cons{car: rawlist[1].Eval(env), cdr: &promise{expr: rawlist[2], env: env}}
+}
+
+func streamCar(rawlist []LispObject, env Environment) LispObject {
+	return checkCons(rawlist[1].Eval(env)).car
+}
+
+func streamCdr(rawlist []LispObject, env Environment) LispObject {
+	rest := checkCons(rawlist[1].Eval(env)).cdr
+	if p, ok := rest.(*promise); ok {
+		return p.Force()
+	}
+	return rest
+}
+
+// (stream-take s 3) -> the first 3 elements of s as a list. walks the stream
+// in a loop so long prefixes don't grow the Go stack
+func streamTake(rawlist []LispObject, env Environment) LispObject {
+	s := rawlist[1].Eval(env)
+	count := rawlist[2].Eval(env)
+	n, ok := count.(fixnum)
+	if !ok || n < 0 {
+		panic("stream-take needs a count, got " + count.Print())
+	}
+	items := []LispObject{}
+	for ; n > 0; n-- {
+		// the tail is only forced once another element is wanted
+		if p, ok := s.(*promise); ok {
+			s = p.Force()
+		}
+		c, ok := s.(*cons)
+		if !ok {
+			break
+		}
+		items = append(items, c.car)
+		s = c.cdr
+	}
+	return list(items...)
+}
@@ -0,0 +1,7 @@
+(lesson "Numbers"
+  "Lisp writes a call as a list with the function first and its arguments after it. Type an expression and press enter."
+  (step "Add 1, 2 and 3." 6 "(+ 1 2 3)")
+  (step "Multiply 6 by 7." 42 "(* 6 7)")
+  (step "Calls nest. Compute 2 times the sum of 3 and 4." 14 "(* 2 (+ 3 4))")
+  (step "Dividing integers gives an exact fraction. Divide 1 by 3." 1/3 "(/ 1 3)")
+  (step "Comparisons give #t or #f. Ask whether 3 is less than 5." #t "(< 3 5)"))
@@ -0,0 +1,7 @@
+(lesson "Lists"
+  "Lists are chains of pairs. quote stops a list from being evaluated as a call."
+  (step "Quote the list (1 2 3)." (1 2 3) "(quote (1 2 3))")
+  (step "Build the same list with list." (1 2 3) "(list 1 2 3)")
+  (step "car gives the first element of a list. Take the car of (a b c)." a "(car (quote (a b c)))")
+  (step "cdr gives everything after it. Take the cdr of (a b c)." (b c) "(cdr (quote (a b c)))")
+  (step "cons puts an element on the front of a list. Put 0 on the front of (1 2)." (0 1 2) "(cons 0 (quote (1 2)))"))
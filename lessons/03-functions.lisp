@@ -0,0 +1,7 @@
+(lesson "Functions"
+  "def names a function, let names values for the expressions in its body, and if picks one of two expressions."
+  (step "Define square, taking x and returning x times x." square "(def square (x) (* x x))")
+  (step "Call square on 12." 144 "(square 12)")
+  (step "Use let to bind a to 3 and b to 4, then add them." 7 "(let ((a 3) (b 4)) (+ a b))")
+  (step "Use if to give yes when 1 equals 1 and no otherwise." yes "(if (= 1 1) (quote yes) (quote no))")
+  (step "Define fact, the factorial of n, then call it on 5." 120 "(def fact (n) (if (= n 0) 1 (* n (fact (- n 1))))) (fact 5)"))
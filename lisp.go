@@ -2,10 +2,16 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
+	"math/big"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 // returns interface{} because that's the only way to ensure that no circular
@@ -20,6 +26,10 @@ func newEnv(length int) (e Environment) {
 	return e
 }
 
+// guards every env's Fields. a lambda keeps a pointer to the env it was made
+// in, so a future's goroutine can be reading bindings the caller is setting
+var envLock sync.RWMutex
+
 type LispObject interface {
 	Eval(env Environment) LispObject
 	Print() string
@@ -28,16 +38,32 @@ type LispObject interface {
 // this is placed here because you can't have circular types in go, but I want
 // to always work with LispObjects
 func (e *Environment) Get(s string) LispObject {
-	if val, ok := e.Fields[s]; ok {
-		return val.(LispObject)
-	}
-	if e.Parent == nil {
-		return Nil
+	envLock.RLock()
+	defer envLock.RUnlock()
+	for env := e; env != nil; env = env.Parent {
+		if val, ok := env.Fields[s]; ok {
+			return val.(LispObject)
+		}
 	}
-	return e.Parent.Get(s)
+	return Nil
 }
 
 func (e *Environment) Put(s string, l LispObject) {
+	envLock.Lock()
+	e.Fields[s] = l
+	envLock.Unlock()
+}
+
+// rebinds s in the nearest env that has it, or binds it in e if none do
+func (e *Environment) Set(s string, l LispObject) {
+	envLock.Lock()
+	defer envLock.Unlock()
+	for env := e; env != nil; env = env.Parent {
+		if _, ok := env.Fields[s]; ok {
+			env.Fields[s] = l
+			return
+		}
+	}
 	e.Fields[s] = l
 }
 
@@ -73,19 +99,185 @@ func (num fixnum) Print() string {
 	return strconv.Itoa(int(num))
 }
 
-type symbol string
+// symbols are interned, so every occurrence of a name is the same *symbol and
+// eq? can compare them by pointer
+type symbol struct {
+	name  string
+	plist []LispObject // alternating keys and values, set with put
+}
+
+var symbolTable = map[string]*symbol{}
+var symbolLock sync.Mutex
+
+// the unique symbol called name, created the first time it's asked for
+func intern(name string) *symbol {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	if s, ok := symbolTable[name]; ok {
+		return s
+	}
+	s := &symbol{name: name}
+	symbolTable[name] = s
+	return s
+}
+
+// the value stored under key in s's property list, keys compared with eq?
+func (s *symbol) GetProp(key LispObject) (LispObject, bool) {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	for i := 0; i < len(s.plist); i += 2 {
+		if eqHelper(s.plist[i], key) {
+			return s.plist[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func (s *symbol) PutProp(key LispObject, val LispObject) {
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	for i := 0; i < len(s.plist); i += 2 {
+		if eqHelper(s.plist[i], key) {
+			s.plist[i+1] = val
+			return
+		}
+	}
+	s.plist = append(s.plist, key, val)
+}
 
 // (eval (x)) where x = 1 -> 1
-func (s symbol) Eval(env Environment) LispObject {
-	return env.Get(string(s))
+func (s *symbol) Eval(env Environment) LispObject {
+	return env.Get(s.name)
+}
+func (s *symbol) Print() string {
+	return s.name
 }
-func (s symbol) Print() string {
-	return string(s)
+
+// self-evaluating names like :port, distinct from the symbol port
+type keyword string
+
+// (eval :port) -> :port
+func (k keyword) Eval(env Environment) LispObject {
+	return k
+}
+func (k keyword) Print() string {
+	return ":" + string(k)
+}
+
+type lispString string
+
+// (eval "abc") -> "abc"
+func (s lispString) Eval(env Environment) LispObject {
+	return s
 }
 
+// quotes and escapes the string so that it reads back in as the same value
+func (s lispString) Print() string {
+	buf := `"`
+	for _, c := range string(s) {
+		switch c {
+		case '"':
+			buf += `\"`
+		case '\\':
+			buf += `\\`
+		case '\n':
+			buf += `\n`
+		case '\t':
+			buf += `\t`
+		default:
+			buf += string(c)
+		}
+	}
+	return buf + `"`
+}
+
+type lispChar rune
+
+// names for characters that can't be written directly after #\
+var charNames = map[string]rune{
+	"space":   ' ',
+	"newline": '\n',
+	"tab":     '\t',
+	"return":  '\r',
+	"nul":     0}
+
+// (eval #\a) -> #\a
+func (c lispChar) Eval(env Environment) LispObject {
+	return c
+}
+func (c lispChar) Print() string {
+	for name, r := range charNames {
+		if r == rune(c) {
+			return `#\` + name
+		}
+	}
+	return `#\` + string(rune(c))
+}
+
+// env is where the lambda was made, so free variables in fn are looked up
+// lexically rather than wherever the lambda happens to be called
+// rest, when set, names the list that collects arguments beyond arglist, and
+// keys are the parameters passed by keyword after them
 type lambda struct {
 	fn      LispObject
 	arglist []string
+	rest    string
+	keys    []keyParam
+	env     *Environment
+}
+
+// a &key parameter and the expression giving its value when it isn't passed
+type keyParam struct {
+	name string
+	def  LispObject
+}
+
+// a new env binding arglist to args, inside the defining env. lambdas built
+// without one fall back to the caller's
+func (l lambda) bind(args []LispObject, caller Environment) Environment {
+	scope := l.env
+	if scope == nil {
+		scope = &caller
+	}
+	if l.rest == "" && l.keys == nil {
+		return scope.FromParent(l.arglist, args)
+	}
+	n := len(l.arglist)
+	if len(args) < n {
+		panic(fmt.Sprintf("expected at least %d arguments, got %d", n, len(args)))
+	}
+	names := append([]string{}, l.arglist...)
+	vals := append([]LispObject{}, args[:n]...)
+	if l.rest != "" {
+		names = append(names, l.rest)
+		vals = append(vals, list(args[n:]...))
+	}
+	if l.keys == nil {
+		return scope.FromParent(names, vals)
+	}
+	passed := map[string]LispObject{}
+	extra := args[n:]
+	for i := 0; i < len(extra); i += 2 {
+		k, ok := extra[i].(keyword)
+		if !ok || i+1 == len(extra) {
+			panic("expected :keyword value pairs, got " + list(extra...).Print())
+		}
+		passed[string(k)] = extra[i+1]
+	}
+	e := scope.FromParent(names, vals)
+	for _, k := range l.keys {
+		val, ok := passed[k.name]
+		if !ok {
+			// defaults can refer to the arguments before them
+			val = k.def.Eval(e)
+		}
+		delete(passed, k.name)
+		e.Put(k.name, val)
+	}
+	for name := range passed {
+		panic("unknown keyword argument :" + name)
+	}
+	return e
 }
 
 // (eval (lambda (x) ()))
@@ -107,219 +299,855 @@ func (i Intrinsic) Print() string {
 	return "<intrinsic>"
 }
 
-type list []LispObject
+// lists are chains of cons cells ending in Nil. a chain ending in anything
+// else is an improper list, printed with a dot before the last element
+type cons struct {
+	car LispObject
+	cdr LispObject
+}
+
+// builds a proper list out of items, () when there are none
+func list(items ...LispObject) LispObject {
+	return listWithTail(items, Nil)
+}
+
+// builds a list out of items whose last cdr is tail, so (1 2 . 3) is
+// listWithTail([1 2], 3)
+func listWithTail(items []LispObject, tail LispObject) LispObject {
+	for i := len(items) - 1; i >= 0; i-- {
+		tail = &cons{car: items[i], cdr: tail}
+	}
+	return tail
+}
+
+// the elements of a proper list, ok is false for improper lists and atoms
+func listToSlice(obj LispObject) (items []LispObject, ok bool) {
+	for {
+		switch v := obj.(type) {
+		case lispNil:
+			return items, true
+		case *cons:
+			items = append(items, v.car)
+			obj = v.cdr
+		default:
+			return items, false
+		}
+	}
+}
+
+// like listToSlice, but panics when obj isn't a proper list
+func toSlice(obj LispObject) []LispObject {
+	items, ok := listToSlice(obj)
+	if !ok {
+		panic("expected a proper list, got " + obj.Print())
+	}
+	return items
+}
+
+// an expression in tail position, handed back by special forms like if so
+// (*cons).Eval can evaluate it in its loop instead of recursing. that keeps
+// tail recursive functions in constant Go stack
+type tailCall struct {
+	expr LispObject
+	env  Environment
+}
+
+func (t tailCall) Eval(env Environment) LispObject {
+	return t.expr.Eval(t.env)
+}
+func (t tailCall) Print() string {
+	return "<tail call " + t.expr.Print() + ">"
+}
+
+// finishes a tail call returned by an intrinsic called from outside Eval
+func resolve(obj LispObject) LispObject {
+	if t, ok := obj.(tailCall); ok {
+		return t.Eval(t.env)
+	}
+	return obj
+}
 
 // (eval (* 1 2)) -> 2
-func (l list) Eval(env Environment) LispObject {
-	first := l[0].Eval(env)
-	context := l[1:]
-	var retVal LispObject = Nil
-	switch f := first.(type) {
+func (c *cons) Eval(env Environment) LispObject {
+	slow := startSlowCall(c)
+	if slow != nil {
+		defer slow.finish()
+	}
+	for {
+		l := toSlice(c)
+		var next LispObject
+		switch f := l[0].Eval(env).(type) {
+		case lambda:
+			context := []LispObject{}
+			for _, arg := range l[1:] {
+				context = append(context, arg.Eval(env))
+			}
+			if slow != nil && c == slow.form {
+				slow.args = context
+			}
+			next, env = f.fn, f.bind(context, env)
+		case Intrinsic:
+			result := f.op(l, env)
+			t, ok := result.(tailCall)
+			if !ok {
+				return result
+			}
+			next, env = t.expr, t.env
+		default:
+			panic("tried to apply a non-lambda value")
+		}
+		call, ok := next.(*cons)
+		if !ok {
+			return next.Eval(env)
+		}
+		c = call
+	}
+}
+
+func (c *cons) Print() string {
+	buf := "(" + c.car.Print()
+	var rest LispObject = c.cdr
+	for {
+		switch v := rest.(type) {
+		case lispNil:
+			return buf + ")"
+		case *cons:
+			buf += " " + v.car.Print()
+			rest = v.cdr
+		default:
+			return buf + " . " + v.Print() + ")"
+		}
+	}
+}
+
+// calls f with already evaluated args. intrinsics evaluate their own arguments,
+// so each one is quoted to keep it from being evaluated a second time
+func apply(f LispObject, args []LispObject, env Environment) LispObject {
+	switch fn := f.(type) {
 	case lambda:
-		e := env.FromParent(f.arglist, context)
-		retVal = f.fn.Eval(e)
+		return fn.fn.Eval(fn.bind(args, env))
 	case Intrinsic:
-		retVal = f.op(l, env)
+		rawlist := []LispObject{fn}
+		for _, arg := range args {
+			rawlist = append(rawlist, list(Intrinsic{op: quote}, arg))
+		}
+		return resolve(fn.op(rawlist, env))
 	default:
 		panic("tried to apply a non-lambda value")
 	}
-	return retVal
 }
 
-func (l list) Print() string {
-	buf := "("
-	for _, val := range l {
-		buf += val.Print() + " "
+// (apply + (quote (1 2))) or (apply + 1 (quote (2))) calls the function with
+// the elements of the last argument, after any arguments before it
+func applyFn(rawlist []LispObject, env Environment) LispObject {
+	if len(rawlist) < 3 {
+		panic("apply needs a function and a list of arguments")
+	}
+	f := rawlist[1].Eval(env)
+	args := []LispObject{}
+	for _, arg := range rawlist[2 : len(rawlist)-1] {
+		args = append(args, arg.Eval(env))
+	}
+	last := rawlist[len(rawlist)-1].Eval(env)
+	rest, ok := listToSlice(last)
+	if !ok {
+		panic("apply's last argument must be a list, got " + last.Print())
+	}
+	return apply(f, append(args, rest...), env)
+}
+
+// (map + (quote (1 2)) (quote (10 20 30))) -> (11 22), stopping at the end of
+// the shortest list
+func mapFn(rawlist []LispObject, env Environment) LispObject {
+	if len(rawlist) < 3 {
+		panic("map needs a function and at least one list")
+	}
+	f := rawlist[1].Eval(env)
+	lists := make([][]LispObject, len(rawlist)-2)
+	n := -1
+	for i, arg := range rawlist[2:] {
+		val := arg.Eval(env)
+		items, ok := listToSlice(val)
+		if !ok {
+			panic("map expects lists, got " + val.Print())
+		}
+		lists[i] = items
+		if n < 0 || len(items) < n {
+			n = len(items)
+		}
 	}
-	return buf + ")"
+	results := make([]LispObject, n)
+	for i := range results {
+		args := make([]LispObject, len(lists))
+		for j, items := range lists {
+			args[j] = items[i]
+		}
+		results[i] = apply(f, args, env)
+	}
+	return list(results...)
+}
+
+// an environment made into a value by the-environment
+type lispEnv struct {
+	env Environment
 }
 
-func mathOp(operation func(fixnum, fixnum) fixnum) Intrinsic {
+func (e lispEnv) Eval(env Environment) LispObject {
+	return e
+}
+func (e lispEnv) Print() string {
+	return "<environment>"
+}
+
+// (the-environment) -> the environment it's evaluated in, for eval
+func theEnvironment(rawlist []LispObject, env Environment) LispObject {
+	return lispEnv{env: env}
+}
+
+// (eval (quote (+ 1 2))) -> 3. the expression is evaluated at top level, or
+// in an environment from the-environment when one is given
+func evalFn(rawlist []LispObject, env Environment) LispObject {
+	expr := rawlist[1].Eval(env)
+	target := *rootEnv(env)
+	if len(rawlist) > 2 {
+		e, ok := rawlist[2].Eval(env).(lispEnv)
+		if !ok {
+			panic("eval needs an environment from the-environment")
+		}
+		target = e.env
+	}
+	return tailCall{expr: expr, env: target}
+}
+
+func mathOp(exactOp func(z, x, y *big.Rat) *big.Rat, floatOp func(flonum, flonum) flonum) Intrinsic {
 	return Intrinsic{op: func(rawlist []LispObject, env Environment) LispObject {
-		total := rawlist[1].Eval(env).(fixnum)
+		total := checkNumber(rawlist[1].Eval(env))
 		for _, obj := range rawlist[2:] {
-			num := obj.Eval(env).(fixnum)
-			total = operation(total, num)
+			total = arith(total, obj.Eval(env), exactOp, floatOp)
 		}
 		return total
 	}}
 }
 
+func checkCons(obj LispObject) *cons {
+	c, ok := obj.(*cons)
+	if !ok {
+		panic("expected a pair, got " + obj.Print())
+	}
+	return c
+}
+
+// (car (quote (1 2))) -> 1
 func car(rawlist []LispObject, env Environment) LispObject {
-	theList := rawlist[1].(list)
-	return theList[0]
+	return checkCons(rawlist[1].Eval(env)).car
 }
 
+// (cdr (quote (1 2))) -> (2)
 func cdr(rawlist []LispObject, env Environment) LispObject {
-	theList := rawlist[1].(list)
-	return theList[1:]
+	return checkCons(rawlist[1].Eval(env)).cdr
+}
+
+// (set-car! p 5) -> 5, changing p in place
+func setCar(rawlist []LispObject, env Environment) LispObject {
+	c := checkCons(rawlist[1].Eval(env))
+	c.car = rawlist[2].Eval(env)
+	return c.car
 }
 
+// (set-cdr! p ()) -> (), changing p in place
+func setCdr(rawlist []LispObject, env Environment) LispObject {
+	c := checkCons(rawlist[1].Eval(env))
+	c.cdr = rawlist[2].Eval(env)
+	return c.cdr
+}
+
+// (cons 1 2) -> (1 . 2)
+func mkcons(rawlist []LispObject, env Environment) LispObject {
+	return &cons{car: rawlist[1].Eval(env), cdr: rawlist[2].Eval(env)}
+}
+
+func isPair(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(*cons)
+	return boolToLisp(ok)
+}
+
+// (lambda (x . rest) ...) and (lambda (x &rest rest) ...) both collect any
+// arguments after x into the list rest. (lambda (x &key port (host "localhost"))
+// ...) takes (f 1 :host "example.com"), binding port to () when it's left out
 func mklambda(rawlist []LispObject, env Environment) LispObject {
-	rawargs := rawlist[1].(list)
-	strargs := []string{}
+	l := lambda{arglist: []string{}, fn: body(rawlist[2:]), env: &env}
+	params := []LispObject{}
+	args := rawlist[1]
+	for {
+		c, ok := args.(*cons)
+		if !ok {
+			break
+		}
+		params = append(params, c.car)
+		args = c.cdr
+	}
+	dotted := ""
+	switch a := args.(type) {
+	case lispNil:
+	case *symbol:
+		dotted = a.name
+	default:
+		panic("lambda expects an argument list")
+	}
+	for i := 0; i < len(params); i++ {
+		if c, ok := params[i].(*cons); ok && l.keys != nil {
+			spec := toSlice(c)
+			name, ok := spec[0].(*symbol)
+			if !ok || len(spec) != 2 {
+				panic("a &key default looks like (name value), got " + c.Print())
+			}
+			l.keys = append(l.keys, keyParam{name: name.name, def: spec[1]})
+			continue
+		}
+		name, ok := params[i].(*symbol)
+		if !ok {
+			panic("lambda arguments must be symbols, got " + params[i].Print())
+		}
+		switch {
+		case name.name == "&rest":
+			if i+1 == len(params) || l.rest != "" || l.keys != nil {
+				panic("&rest must be followed by exactly one name")
+			}
+			i++
+			rest, ok := params[i].(*symbol)
+			if !ok {
+				panic("&rest must be followed by exactly one name")
+			}
+			l.rest = rest.name
+		case name.name == "&key":
+			if l.keys != nil {
+				panic("&key can only appear once")
+			}
+			l.keys = []keyParam{}
+		case l.keys != nil:
+			l.keys = append(l.keys, keyParam{name: name.name, def: Nil})
+		case l.rest != "":
+			panic("&rest must be followed by exactly one name")
+		default:
+			l.arglist = append(l.arglist, name.name)
+		}
+	}
+	if dotted != "" {
+		if l.rest != "" || l.keys != nil {
+			panic("a dotted rest argument can't follow &rest or &key")
+		}
+		l.rest = dotted
+	}
+	return l
+}
+
+// (def foo (x) x) -> foo
+func def(rawlist []LispObject, env Environment) LispObject {
+	name := rawlist[1].(*symbol)
+	env.Put(name.name, mklambda(rawlist[1:], env))
+	return name
+}
 
-	for i := range rawargs {
-		strargs = append(strargs, string(rawargs[i].(symbol)))
+// true for (def ...) forms, which the repl reports by name instead of echoing
+func isDefinition(obj LispObject) bool {
+	c, ok := obj.(*cons)
+	if !ok {
+		return false
 	}
+	head, ok := c.car.(*symbol)
+	return ok && head == intern("def")
+}
+
+type lispBool bool
+
+var True lispBool = lispBool(true)
+var False lispBool = lispBool(false)
 
-	return lambda{
-		arglist: strargs,
-		fn:      rawlist[2]}
+// (eval #t) -> #t
+func (b lispBool) Eval(env Environment) LispObject {
+	return b
+}
+func (b lispBool) Print() string {
+	if b {
+		return "#t"
+	}
+	return "#f"
 }
 
-func def(rawlist []LispObject, env Environment) LispObject {
-	lam := mklambda(rawlist[1:], env)
-	env.Put(string(rawlist[1].(symbol)), lam)
-	return lam
+// predicates always return #t or #f
+func boolToLisp(b bool) LispObject {
+	if b {
+		return True
+	}
+	return False
 }
+
+// #f and () are the only false values, everything else counts as true
 func lispToBool(l LispObject) bool {
-	switch l.(type) {
+	switch v := l.(type) {
 	case lispNil:
 		return false
+	case lispBool:
+		return bool(v)
 	default:
 		return true
 	}
-	return true
 }
 
+// (if #f 1) -> (), the else branch is optional
 func If(rawlist []LispObject, env Environment) LispObject {
-	if lispToBool(rawlist[1]) {
-		return rawlist[2].Eval(env)
-	} else {
-		return rawlist[3].Eval(env)
+	if lispToBool(rawlist[1].Eval(env)) {
+		return tailCall{expr: rawlist[2], env: env}
+	} else if len(rawlist) > 3 {
+		return tailCall{expr: rawlist[3], env: env}
 	}
 	return Nil
 }
-func boolOp(fn func(bool, bool) bool) Intrinsic {
+
+// (and a b ...) and (or a b ...) stop evaluating as soon as an argument
+// settles the answer, which is #t or #f. stopOn is the truth value that does
+func boolOp(stopOn bool) Intrinsic {
 	return Intrinsic{
 		op: func(rawlist []LispObject, env Environment) LispObject {
-			a := lispToBool(rawlist[1].Eval(env))
-			b := lispToBool(rawlist[2].Eval(env))
-			if fn(a, b) {
-				return Nil
-			} else {
-				return fixnum(1)
+			for _, arg := range rawlist[1:] {
+				if lispToBool(arg.Eval(env)) == stopOn {
+					return boolToLisp(stopOn)
+				}
 			}
-			return fixnum(1)
+			return boolToLisp(!stopOn)
 		}}
 }
 
-func compOp(fn func(fixnum, fixnum) bool) Intrinsic {
+// (not ()) -> #t
+func not(rawlist []LispObject, env Environment) LispObject {
+	return boolToLisp(!lispToBool(rawlist[1].Eval(env)))
+}
+
+// (boolean 5) -> #t
+func boolean(rawlist []LispObject, env Environment) LispObject {
+	return boolToLisp(lispToBool(rawlist[1].Eval(env)))
+}
+
+// (< 1 2 3) holds when fn accepts the numeric comparison of every adjacent pair
+// of arguments
+func compOp(fn func(int) bool) Intrinsic {
 	return Intrinsic{
 		op: func(rawlist []LispObject, env Environment) LispObject {
-			a := rawlist[1].Eval(env).(fixnum)
-			b := rawlist[2].Eval(env).(fixnum)
-			if fn(a, b) {
-				return Nil
-			} else {
-				return fixnum(1)
+			a := rawlist[1].Eval(env)
+			for _, obj := range rawlist[2:] {
+				b := obj.Eval(env)
+				if !fn(compareNumbers(a, b)) {
+					return False
+				}
+				a = b
 			}
-			return fixnum(1)
+			return True
 		}}
 }
 
+// orders numbers numerically, symbols, keywords and strings by name, chars by
+// code point and times chronologically, returning -1, 0 or 1
+func compareHelper(a, b LispObject) int {
+	if isNumber(a) && isNumber(b) {
+		return compareNumbers(a, b)
+	}
+	switch v1 := a.(type) {
+	case *symbol:
+		if v2, ok := b.(*symbol); ok {
+			return strings.Compare(v1.name, v2.name)
+		}
+	case lispString:
+		if v2, ok := b.(lispString); ok {
+			return strings.Compare(string(v1), string(v2))
+		}
+	case keyword:
+		if v2, ok := b.(keyword); ok {
+			return strings.Compare(string(v1), string(v2))
+		}
+	case lispChar:
+		if v2, ok := b.(lispChar); ok {
+			return compareNumbers(fixnum(v1), fixnum(v2))
+		}
+	case lispTime:
+		if v2, ok := b.(lispTime); ok {
+			return v1.t.Compare(v2.t)
+		}
+	}
+	panic("compare: can't order " + a.Print() + " and " + b.Print())
+}
+
+func compare(rawlist []LispObject, env Environment) LispObject {
+	return fixnum(compareHelper(rawlist[1].Eval(env), rawlist[2].Eval(env)))
+}
+
+// (set! x 5) -> 5
 func set(rawlist []LispObject, env Environment) LispObject {
-	sym := rawlist[1].(symbol)
-	env.Put(string(sym), rawlist[2].Eval(env))
-	return Nil
+	sym, ok := rawlist[1].(*symbol)
+	if !ok {
+		panic("set! needs a name, got " + rawlist[1].Print())
+	}
+	val := rawlist[2].Eval(env)
+	env.Set(sym.name, val)
+	return val
 }
 func quote(rawlist []LispObject, env Environment) LispObject {
 	return rawlist[1]
 }
+
+// (list 1 (+ 1 1)) -> (1 2)
 func toList(rawlist []LispObject, env Environment) LispObject {
-	return list(rawlist[1:])
+	items := make([]LispObject, len(rawlist)-1)
+	for i, arg := range rawlist[1:] {
+		items[i] = arg.Eval(env)
+	}
+	return list(items...)
 }
+
+// (append (quote (1 2)) 3) -> (1 2 3). copies the list so the original is
+// left alone, as it would be shared structure now that lists are cons cells
 func appendList(rawlist []LispObject, env Environment) LispObject {
-	l := rawlist[1].Eval(env).(list)
-	return append(l, rawlist[2])
+	l := toSlice(rawlist[1].Eval(env))
+	return list(append(l, rawlist[2].Eval(env))...)
 }
+
+// the names and unevaluated value forms of the bindings of a let-style form,
+// which may be empty. anything that isn't a (name value) list is an error
+func letBindings(form string, rawlist []LispObject) ([]string, []LispObject) {
+	if len(rawlist) < 2 {
+		panic(form + " needs a list of bindings")
+	}
+	bindings, ok := listToSlice(rawlist[1])
+	if !ok {
+		panic(form + " needs a list of bindings, got " + rawlist[1].Print())
+	}
+	names := make([]string, len(bindings))
+	vals := make([]LispObject, len(bindings))
+	for i, obj := range bindings {
+		binding, ok := listToSlice(obj)
+		if !ok || len(binding) != 2 {
+			panic(fmt.Sprintf("%v binding %d is not a (name value) pair: %v", form, i+1, obj.Print()))
+		}
+		name, ok := binding[0].(*symbol)
+		if !ok {
+			panic(fmt.Sprintf("%v binding %d needs a name, got %v", form, i+1, binding[0].Print()))
+		}
+		names[i] = name.name
+		vals[i] = binding[1]
+	}
+	return names, vals
+}
+
+// (let ((x 1) (y 2)) (+ x y)) -> 3. the values are all evaluated in the
+// enclosing environment
 func let(rawlist []LispObject, env Environment) LispObject {
-	args := []string{}
-	context := []LispObject{}
-	arglist := rawlist[1].(list)
+	if len(rawlist) > 2 {
+		if name, ok := rawlist[1].(*symbol); ok {
+			return namedLet(name, rawlist[1:], env)
+		}
+	}
+	names, vals := letBindings("let", rawlist)
+	context := make([]LispObject, len(vals))
+	for i, val := range vals {
+		context[i] = val.Eval(env)
+	}
+	e := env.FromParent(names, context)
+	return tailCall{expr: body(rawlist[2:]), env: e}
+}
+
+// (let loop ((i 0) (acc ())) (if (= i 3) acc (loop (+ i 1) (cons i acc))))
+// -> (2 1 0). loop is bound inside the body to a function of the bindings,
+// which starts off called with their values. rawlist starts at the name
+func namedLet(name *symbol, rawlist []LispObject, env Environment) LispObject {
+	names, vals := letBindings("let", rawlist)
+	args := make([]LispObject, len(vals))
+	for i, val := range vals {
+		args[i] = val.Eval(env)
+	}
+	scope := env.FromParent(nil, nil)
+	loop := lambda{fn: body(rawlist[2:]), arglist: names, env: &scope}
+	scope.Put(name.name, loop)
+	return tailCall{expr: loop.fn, env: loop.bind(args, env)}
+}
+
+// (let* ((x 1) (y (+ x 1))) y) -> 2. each binding is evaluated with the ones
+// before it in scope
+func letStar(rawlist []LispObject, env Environment) LispObject {
+	names, vals := letBindings("let*", rawlist)
+	e := env.FromParent(nil, nil)
+	for i, val := range vals {
+		e.Put(names[i], val.Eval(e))
+	}
+	return tailCall{expr: body(rawlist[2:]), env: e}
+}
+
+// (letrec ((even? (lambda (n) ... (odd? ...))) (odd? ...)) (even? 10)). every
+// binding is in scope while the values are evaluated, so lambdas can call
+// each other. a value that uses another binding directly sees ()
+func letrec(rawlist []LispObject, env Environment) LispObject {
+	names, vals := letBindings("letrec", rawlist)
+	e := env.FromParent(nil, nil)
+	for _, name := range names {
+		e.Put(name, Nil)
+	}
+	for i, val := range vals {
+		e.Put(names[i], val.Eval(e))
+	}
+	return tailCall{expr: body(rawlist[2:]), env: e}
+}
+
+// (while (< i 3) (set! i (+ i 1))) runs the body for as long as the test
+// holds, returning ()
+func while(rawlist []LispObject, env Environment) LispObject {
+	if len(rawlist) < 2 {
+		panic("while needs a test")
+	}
+	loop := body(rawlist[2:])
+	for lispToBool(rawlist[1].Eval(env)) {
+		loop.Eval(env)
+	}
+	return Nil
+}
+
+// (do ((i 0 (+ i 1)) (acc () (cons i acc))) ((= i 3) acc) body...) -> (2 1 0).
+// each pass evaluates the steps in the old bindings and rebinds the variables
+// to them in a fresh environment, leaving out a step keeps the variable as is.
+// once the test holds the result forms are evaluated, giving () if there are
+// none
+func do(rawlist []LispObject, env Environment) LispObject {
+	if len(rawlist) < 3 {
+		panic("do needs a list of variables and an end clause")
+	}
+	specs, ok := listToSlice(rawlist[1])
+	if !ok {
+		panic("do needs a list of variables, got " + rawlist[1].Print())
+	}
+	names := make([]string, len(specs))
+	vals := make([]LispObject, len(specs))
+	steps := make([]LispObject, len(specs))
+	for i, obj := range specs {
+		spec, ok := listToSlice(obj)
+		if !ok || len(spec) < 2 || len(spec) > 3 {
+			panic(fmt.Sprintf("do variable %d is not a (name init step) list: %v", i+1, obj.Print()))
+		}
+		name, ok := spec[0].(*symbol)
+		if !ok {
+			panic(fmt.Sprintf("do variable %d needs a name, got %v", i+1, spec[0].Print()))
+		}
+		names[i] = name.name
+		vals[i] = spec[1].Eval(env)
+		if len(spec) == 3 {
+			steps[i] = spec[2]
+		}
+	}
+	end, ok := nonEmptyList(rawlist[2])
+	if !ok {
+		panic("do needs a (test result...) end clause, got " + rawlist[2].Print())
+	}
+	loop := body(rawlist[3:])
+	for {
+		e := env.FromParent(names, vals)
+		if lispToBool(end[0].Eval(e)) {
+			return tailCall{expr: body(end[1:]), env: e}
+		}
+		loop.Eval(e)
+		vals = make([]LispObject, len(names))
+		for i, step := range steps {
+			if step == nil {
+				vals[i] = e.Get(names[i])
+			} else {
+				vals[i] = step.Eval(e)
+			}
+		}
+	}
+}
+
+// (cond ((< x 0) (quote neg)) ((= x 0) (quote zero)) (else (quote pos)))
+// runs the body of the first clause whose test holds. a clause without a body
+// gives the test's value, and with no match the result is ()
+func cond(rawlist []LispObject, env Environment) LispObject {
+	for i, obj := range rawlist[1:] {
+		clause, ok := nonEmptyList(obj)
+		if !ok {
+			panic(fmt.Sprintf("cond clause %d is not a list", i+1))
+		}
+		if clause[0] == intern("else") {
+			return tailCall{expr: body(clause[1:]), env: env}
+		}
+		if test := clause[0].Eval(env); lispToBool(test) {
+			if len(clause) == 1 {
+				return test
+			}
+			return tailCall{expr: body(clause[1:]), env: env}
+		}
+	}
+	return Nil
+}
+
+// (case (car tokens) ((+ -) :additive) ((* /) :multiplicative) (else :other))
+// evaluates the key once and runs the first clause listing an equal? datum.
+// the data aren't evaluated. with no match and no else the result is ()
+func caseForm(rawlist []LispObject, env Environment) LispObject {
+	key := rawlist[1].Eval(env)
+	for i, obj := range rawlist[2:] {
+		clause, ok := listToSlice(obj)
+		if !ok || len(clause) == 0 {
+			panic(fmt.Sprintf("case clause %d is not a list", i+1))
+		}
+		if s, ok := clause[0].(*symbol); ok && s.name == "else" {
+			return tailCall{expr: body(clause[1:]), env: env}
+		}
+		data, ok := listToSlice(clause[0])
+		if !ok {
+			panic(fmt.Sprintf("case clause %d should start with a list of data", i+1))
+		}
+		for _, datum := range data {
+			if equalHelper(key, datum) {
+				return tailCall{expr: body(clause[1:]), env: env}
+			}
+		}
+	}
+	return Nil
+}
+
+// (begin (print 1) 2) -> 2, evaluating each form in order
+func begin(rawlist []LispObject, env Environment) LispObject {
+	if len(rawlist) == 1 {
+		return Nil
+	}
+	for _, form := range rawlist[1 : len(rawlist)-1] {
+		form.Eval(env)
+	}
+	return tailCall{expr: rawlist[len(rawlist)-1], env: env}
+}
 
-	for _, argcons := range arglist {
-		cons := argcons.(list)
-		name := cons[0].(symbol)
-		val := cons[1].Eval(env)
-		args = append(args, string(name))
-		context = append(context, val)
+// a single expression for the body forms of a lambda or let, wrapping several
+// in a begin
+func body(forms []LispObject) LispObject {
+	switch len(forms) {
+	case 0:
+		return Nil
+	case 1:
+		return forms[0]
 	}
-	e := env.FromParent(args, context)
-	return rawlist[2].Eval(e)
+	return list(append([]LispObject{Intrinsic{op: begin}}, forms...)...)
 }
 func length(rawlist []LispObject, env Environment) LispObject {
 	switch v := rawlist[1].Eval(env).(type) {
-	case list:
-		return fixnum(len(v))
+	case *cons:
+		return fixnum(len(toSlice(v)))
 	case lispNil:
 		return fixnum(0)
+	case *hashTable:
+		return fixnum(v.size)
+	case *deque:
+		return fixnum(v.size)
 	default:
 		return fixnum(1)
 	}
 	return fixnum(0)
 }
 
+// (print "x" 1) writes "x" 1 and a newline to stdout, returning ()
 func print(rawlist []LispObject, env Environment) LispObject {
-	for _, val := range rawlist {
-		fmt.Print(val.Print())
+	vals := make([]string, len(rawlist)-1)
+	for i, arg := range rawlist[1:] {
+		vals[i] = arg.Eval(env).Print()
 	}
+	fmt.Fprintln(printOut, strings.Join(vals, " "))
 	return Nil
 }
 
-func eq(rawlist []LispObject, env Environment) LispObject {
-	a := rawlist[1].Eval(env)
-	b := rawlist[2].Eval(env)
-
+// identity comparison. pairs, records and hashes are only eq? to themselves,
+// atoms are compared by value
+func eqHelper(a, b LispObject) bool {
 	switch v1 := a.(type) {
-	case list:
-		if v2, ok := b.(list); ok {
-			if &v1 == &v2 {
-				return fixnum(1)
-			}
-			return Nil
+	case *cons:
+		if v2, ok := b.(*cons); ok {
+			return v1 == v2
 		}
-		return Nil
+		return false
+	case *record, *hashTable:
+		return a == b
 	case fixnum:
 		if v2, ok := b.(fixnum); ok {
 			if v1 == v2 {
-				return fixnum(1)
+				return true
 			}
-			return Nil
+			return false
 		}
-		return Nil
-	case symbol:
-		if v2, ok := b.(symbol); ok {
+		return false
+	case *symbol:
+		if v2, ok := b.(*symbol); ok {
 			if v1 == v2 {
-				return fixnum(1)
+				return true
 			}
-			return Nil
+			return false
 		}
-		return Nil
+		return false
+	case lispString:
+		if v2, ok := b.(lispString); ok {
+			return v1 == v2
+		}
+		return false
+	case keyword:
+		if v2, ok := b.(keyword); ok {
+			return v1 == v2
+		}
+		return false
+	case lispChar:
+		if v2, ok := b.(lispChar); ok {
+			return v1 == v2
+		}
+		return false
+	case flonum:
+		if v2, ok := b.(flonum); ok {
+			return v1 == v2
+		}
+		return false
+	case bignum:
+		if v2, ok := b.(bignum); ok {
+			return v1.val.Cmp(v2.val) == 0
+		}
+		return false
+	case ratnum:
+		if v2, ok := b.(ratnum); ok {
+			return v1.val.Cmp(v2.val) == 0
+		}
+		return false
+	case decimal:
+		if v2, ok := b.(decimal); ok {
+			return v1.Rat().Cmp(v2.Rat()) == 0
+		}
+		return false
+	case lispTime:
+		if v2, ok := b.(lispTime); ok {
+			return v1.t.Equal(v2.t)
+		}
+		return false
 	case lispNil:
 		if _, ok := b.(lispNil); ok {
-			return fixnum(1)
+			return true
 		}
-		return Nil
+		return false
+	case lispBool:
+		if v2, ok := b.(lispBool); ok {
+			return v1 == v2
+		}
+		return false
 	}
 
-	return Nil
+	return false
+}
+
+func eq(rawlist []LispObject, env Environment) LispObject {
+	return boolToLisp(eqHelper(rawlist[1].Eval(env), rawlist[2].Eval(env)))
 }
 
 func equalHelper(a, b LispObject) bool {
 	switch v1 := a.(type) {
-	case list:
-		if v2, ok := b.(list); ok {
-			if len(v1) != len(v2) {
-				return false
-			}
-			for i := range v1 {
-				if !equalHelper(v1[i], v2[i]) {
-					return false
-				}
-			}
-			return true
+	case *cons:
+		if v2, ok := b.(*cons); ok {
+			return equalHelper(v1.car, v2.car) && equalHelper(v1.cdr, v2.cdr)
 		}
 		return false
 	case fixnum:
@@ -330,19 +1158,74 @@ func equalHelper(a, b LispObject) bool {
 			return false
 		}
 		return false
-	case symbol:
-		if v2, ok := b.(symbol); ok {
+	case *symbol:
+		if v2, ok := b.(*symbol); ok {
 			if v1 == v2 {
 				return true
 			}
 			return false
 		}
 		return false
+	case lispString:
+		if v2, ok := b.(lispString); ok {
+			return v1 == v2
+		}
+		return false
+	case keyword:
+		if v2, ok := b.(keyword); ok {
+			return v1 == v2
+		}
+		return false
+	case lispChar:
+		if v2, ok := b.(lispChar); ok {
+			return v1 == v2
+		}
+		return false
+	case flonum:
+		if v2, ok := b.(flonum); ok {
+			return v1 == v2
+		}
+		return false
+	case bignum:
+		if v2, ok := b.(bignum); ok {
+			return v1.val.Cmp(v2.val) == 0
+		}
+		return false
+	case ratnum:
+		if v2, ok := b.(ratnum); ok {
+			return v1.val.Cmp(v2.val) == 0
+		}
+		return false
+	case decimal:
+		if v2, ok := b.(decimal); ok {
+			return v1.Rat().Cmp(v2.Rat()) == 0
+		}
+		return false
 	case lispNil:
 		if _, ok := b.(lispNil); ok {
 			return true
 		}
 		return false
+	case lispBool:
+		if v2, ok := b.(lispBool); ok {
+			return v1 == v2
+		}
+		return false
+	case lispTime:
+		if v2, ok := b.(lispTime); ok {
+			return v1.t.Equal(v2.t)
+		}
+		return false
+	case *record:
+		if v2, ok := b.(*record); ok && v1.typ == v2.typ {
+			for i := range v1.fields {
+				if !equalHelper(v1.fields[i], v2.fields[i]) {
+					return false
+				}
+			}
+			return true
+		}
+		return false
 	}
 	return false
 }
@@ -350,145 +1233,770 @@ func equalHelper(a, b LispObject) bool {
 func equal(rawlist []LispObject, env Environment) LispObject {
 	a := rawlist[1].Eval(env)
 	b := rawlist[2].Eval(env)
-	if equalHelper(a, b) {
-		return fixnum(1)
+	return boolToLisp(equalHelper(a, b))
+}
+
+// collects a (path expected actual) entry for every place a and b differ, where
+// path is the list of indexes leading to the differing element
+func diffHelper(path []LispObject, a, b LispObject) []LispObject {
+	v1, ok1 := listToSlice(a)
+	v2, ok2 := listToSlice(b)
+	if !ok1 || !ok2 || len(v1) != len(v2) {
+		if equalHelper(a, b) {
+			return nil
+		}
+		return []LispObject{list(list(path...), a, b)}
 	}
-	return Nil
+	diffs := []LispObject{}
+	for i := range v1 {
+		p := append(append([]LispObject{}, path...), fixnum(i))
+		diffs = append(diffs, diffHelper(p, v1[i], v2[i])...)
+	}
+	return diffs
 }
 
-func isNil(rawlist []LispObject, env Environment) LispObject {
-	if _, ok := rawlist[1].Eval(env).(lispNil); ok {
-		return fixnum(1)
+// (diff (quote (1 (2 3))) (quote (1 (2 4)))) -> (((1 1) 3 4))
+func diff(rawlist []LispObject, env Environment) LispObject {
+	return list(diffHelper(nil, rawlist[1].Eval(env), rawlist[2].Eval(env))...)
+}
+
+// the elements of obj when it's a non-empty proper list. the tree walkers treat
+// everything else, including dotted pairs, as a leaf
+func nonEmptyList(obj LispObject) ([]LispObject, bool) {
+	if _, ok := obj.(*cons); !ok {
+		return nil, false
 	}
-	return Nil
+	return listToSlice(obj)
+}
+
+func prewalkHelper(f LispObject, form LispObject, env Environment) LispObject {
+	form = apply(f, []LispObject{form}, env)
+	if l, ok := nonEmptyList(form); ok {
+		walked := []LispObject{}
+		for _, val := range l {
+			walked = append(walked, prewalkHelper(f, val, env))
+		}
+		return list(walked...)
+	}
+	return form
+}
+
+func postwalkHelper(f LispObject, form LispObject, env Environment) LispObject {
+	if l, ok := nonEmptyList(form); ok {
+		walked := []LispObject{}
+		for _, val := range l {
+			walked = append(walked, postwalkHelper(f, val, env))
+		}
+		form = list(walked...)
+	}
+	return apply(f, []LispObject{form}, env)
+}
+
+// applies f to form and then to each element of the result, top down
+func prewalk(rawlist []LispObject, env Environment) LispObject {
+	return prewalkHelper(rawlist[1].Eval(env), rawlist[2].Eval(env), env)
+}
+
+// applies f to each element of form and then to the rebuilt form, bottom up
+func postwalk(rawlist []LispObject, env Environment) LispObject {
+	return postwalkHelper(rawlist[1].Eval(env), rawlist[2].Eval(env), env)
+}
+
+// the forms directly inside form that match a single pattern step. * matches any
+// child, any other step matches child lists whose first element is equal to it
+func selectStep(form LispObject, step LispObject) []LispObject {
+	l, ok := nonEmptyList(form)
+	if !ok {
+		return nil
+	}
+	matches := []LispObject{}
+	for _, child := range l {
+		if step == intern("*") {
+			matches = append(matches, child)
+		} else if c, ok := child.(*cons); ok && equalHelper(c.car, step) {
+			matches = append(matches, child)
+		}
+	}
+	return matches
+}
+
+// form and every form nested inside it, used for the ** step
+func descendants(form LispObject) []LispObject {
+	forms := []LispObject{form}
+	if l, ok := nonEmptyList(form); ok {
+		for _, child := range l {
+			forms = append(forms, descendants(child)...)
+		}
+	}
+	return forms
+}
+
+// (sexp-select (quote (cfg (srv (port 80)) (srv (port 81)))) (quote (srv port)))
+// -> ((port 80) (port 81))
+func sexpSelect(rawlist []LispObject, env Environment) LispObject {
+	current := []LispObject{rawlist[1].Eval(env)}
+	pattern, ok := nonEmptyList(rawlist[2].Eval(env))
+	if !ok {
+		panic("sexp-select expects a list pattern")
+	}
+	for _, step := range pattern {
+		next := []LispObject{}
+		for _, form := range current {
+			if step == intern("**") {
+				next = append(next, descendants(form)...)
+			} else {
+				next = append(next, selectStep(form, step)...)
+			}
+		}
+		current = next
+	}
+	return list(current...)
+}
+
+func isNil(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(lispNil)
+	return boolToLisp(ok)
 }
 func isSymbol(rawlist []LispObject, env Environment) LispObject {
-	if _, ok := rawlist[1].Eval(env).(symbol); ok {
-		return fixnum(1)
+	_, ok := rawlist[1].Eval(env).(*symbol)
+	return boolToLisp(ok)
+}
+
+// (symbol->string (quote abc)) -> "abc"
+func symbolToString(rawlist []LispObject, env Environment) LispObject {
+	return lispString(rawlist[1].Eval(env).(*symbol).name)
+}
+
+// (string->symbol "abc") -> abc
+func stringToSymbol(rawlist []LispObject, env Environment) LispObject {
+	return intern(string(rawlist[1].Eval(env).(lispString)))
+}
+
+// (put (quote x) :color (quote red)) -> red
+func put(rawlist []LispObject, env Environment) LispObject {
+	sym := rawlist[1].Eval(env).(*symbol)
+	val := rawlist[3].Eval(env)
+	sym.PutProp(rawlist[2].Eval(env), val)
+	return val
+}
+
+// (get (quote x) :color) -> red, or () when x has no :color property
+func get(rawlist []LispObject, env Environment) LispObject {
+	sym := rawlist[1].Eval(env).(*symbol)
+	if val, ok := sym.GetProp(rawlist[2].Eval(env)); ok {
+		return val
 	}
 	return Nil
 }
+
+func symbolPlist(rawlist []LispObject, env Environment) LispObject {
+	sym := rawlist[1].Eval(env).(*symbol)
+	symbolLock.Lock()
+	defer symbolLock.Unlock()
+	return list(sym.plist...)
+}
+
+func isKeyword(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(keyword)
+	return boolToLisp(ok)
+}
+func isBoolean(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(lispBool)
+	return boolToLisp(ok)
+}
+func isChar(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(lispChar)
+	return boolToLisp(ok)
+}
+
+// (char->int #\a) -> 97
+func charToInt(rawlist []LispObject, env Environment) LispObject {
+	return fixnum(rawlist[1].Eval(env).(lispChar))
+}
+
+// (int->char 97) -> #\a
+func intToChar(rawlist []LispObject, env Environment) LispObject {
+	return lispChar(rawlist[1].Eval(env).(fixnum))
+}
+
+func isString(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(lispString)
+	return boolToLisp(ok)
+}
+func isNum(rawlist []LispObject, env Environment) LispObject {
+	return boolToLisp(isNumber(rawlist[1].Eval(env)))
+}
 func isFixnum(rawlist []LispObject, env Environment) LispObject {
-	if _, ok := rawlist[1].Eval(env).(fixnum); ok {
-		return fixnum(1)
-	}
-	return Nil
+	_, ok := rawlist[1].Eval(env).(fixnum)
+	return boolToLisp(ok)
 }
+
+// true for proper lists, including ()
 func isList(rawlist []LispObject, env Environment) LispObject {
-	if _, ok := rawlist[1].Eval(env).(list); ok {
-		return fixnum(1)
-	}
-	return Nil
+	_, ok := listToSlice(rawlist[1].Eval(env))
+	return boolToLisp(ok)
 }
 func isLambda(rawlist []LispObject, env Environment) LispObject {
-	if _, ok := rawlist[1].Eval(env).(lambda); ok {
-		return fixnum(1)
-	}
-	return Nil
+	_, ok := rawlist[1].Eval(env).(lambda)
+	return boolToLisp(ok)
 }
 func isIntrinsic(rawlist []LispObject, env Environment) LispObject {
-	if _, ok := rawlist[1].Eval(env).(Intrinsic); ok {
-		return fixnum(1)
-	}
-	return Nil
+	_, ok := rawlist[1].Eval(env).(Intrinsic)
+	return boolToLisp(ok)
 }
 
 var IntrinsicList map[string]Intrinsic = map[string]Intrinsic{
-	"+":          mathOp(func(a fixnum, b fixnum) fixnum { return a + b }),
-	"-":          mathOp(func(a fixnum, b fixnum) fixnum { return a - b }),
-	"*":          mathOp(func(a fixnum, b fixnum) fixnum { return a * b }),
-	"/":          mathOp(func(a fixnum, b fixnum) fixnum { return a / b }),
-	"car":        Intrinsic{op: car},
-	"cdr":        Intrinsic{op: cdr},
-	"lambda":     Intrinsic{op: mklambda},
-	"def":        Intrinsic{op: def},
-	"if":         Intrinsic{op: If},
-	"and":        boolOp(func(a bool, b bool) bool { return a && b }),
-	"or":         boolOp(func(a bool, b bool) bool { return a || b }),
-	">":          compOp(func(a fixnum, b fixnum) bool { return a > b }),
-	">=":         compOp(func(a fixnum, b fixnum) bool { return a >= b }),
-	"<":          compOp(func(a fixnum, b fixnum) bool { return a < b }),
-	"<=":         compOp(func(a fixnum, b fixnum) bool { return a <= b }),
-	"set!":       Intrinsic{op: set},
-	"quote":      Intrinsic{op: quote},
-	"list":       Intrinsic{op: toList},
-	"append":     Intrinsic{op: appendList},
-	"let":        Intrinsic{op: let},
-	"length":     Intrinsic{op: length},
-	"print":      Intrinsic{op: print},
-	"eq?":        Intrinsic{op: eq},
-	"equal?":     Intrinsic{op: equal},
-	"nil?":       Intrinsic{op: isNil},
-	"symbol?":    Intrinsic{op: isSymbol},
-	"num?":       Intrinsic{op: isFixnum},
-	"list?":      Intrinsic{op: isList},
-	"lambda?":    Intrinsic{op: isLambda},
-	"intrinsic?": Intrinsic{op: isIntrinsic}}
+	"+": mathOp((*big.Rat).Add,
+		func(a flonum, b flonum) flonum { return a + b }),
+	"-": mathOp((*big.Rat).Sub,
+		func(a flonum, b flonum) flonum { return a - b }),
+	"*": mathOp((*big.Rat).Mul,
+		func(a flonum, b flonum) flonum { return a * b }),
+	"/": mathOp((*big.Rat).Quo,
+		func(a flonum, b flonum) flonum { return a / b }),
+	"car":            Intrinsic{op: car},
+	"cdr":            Intrinsic{op: cdr},
+	"cons":           Intrinsic{op: mkcons},
+	"lambda":         Intrinsic{op: mklambda},
+	"def":            Intrinsic{op: def},
+	"defstruct":      Intrinsic{op: defstruct},
+	"if":             Intrinsic{op: If},
+	"and":            boolOp(false),
+	"or":             boolOp(true),
+	"not":            Intrinsic{op: not},
+	"boolean":        Intrinsic{op: boolean},
+	">":              compOp(func(c int) bool { return c > 0 }),
+	">=":             compOp(func(c int) bool { return c >= 0 }),
+	"<":              compOp(func(c int) bool { return c < 0 }),
+	"<=":             compOp(func(c int) bool { return c <= 0 }),
+	"=":              compOp(func(c int) bool { return c == 0 }),
+	"compare":        Intrinsic{op: compare},
+	"set!":           Intrinsic{op: set},
+	"quote":          Intrinsic{op: quote},
+	"list":           Intrinsic{op: toList},
+	"append":         Intrinsic{op: appendList},
+	"let":            Intrinsic{op: let},
+	"length":         Intrinsic{op: length},
+	"print":          Intrinsic{op: print},
+	"eq?":            Intrinsic{op: eq},
+	"equal?":         Intrinsic{op: equal},
+	"diff":           Intrinsic{op: diff},
+	"prewalk":        Intrinsic{op: prewalk},
+	"postwalk":       Intrinsic{op: postwalk},
+	"sexp-select":    Intrinsic{op: sexpSelect},
+	"dot-graph":      Intrinsic{op: mkDotGraph},
+	"delay":          Intrinsic{op: delay},
+	"force":          Intrinsic{op: force},
+	"promise?":       Intrinsic{op: isPromise},
+	"stream-cons":    Intrinsic{op: streamCons},
+	"stream-car":     Intrinsic{op: streamCar},
+	"stream-cdr":     Intrinsic{op: streamCdr},
+	"stream-take":    Intrinsic{op: streamTake},
+	"uses":           Intrinsic{op: uses},
+	"used-by":        Intrinsic{op: usedBy},
+	"assoc":          Intrinsic{op: assoc},
+	"assq":           Intrinsic{op: assq},
+	"acons":          Intrinsic{op: acons},
+	"alist-get":      Intrinsic{op: alistGet},
+	"alist->hash":    Intrinsic{op: alistToHash},
+	"make-hash":      Intrinsic{op: makeHash},
+	"now":            Intrinsic{op: now},
+	"let*":           Intrinsic{op: letStar},
+	"letrec":         Intrinsic{op: letrec},
+	"while":          Intrinsic{op: while},
+	"do":             Intrinsic{op: do},
+	"cond":           Intrinsic{op: cond},
+	"generator":      Intrinsic{op: mkGenerator},
+	"generator?":     Intrinsic{op: isGenerator},
+	"next":           Intrinsic{op: next},
+	"quasiquote":     Intrinsic{op: quasiquote},
+	"unquote":        Intrinsic{op: unquoteOutside},
+	"apply":          Intrinsic{op: applyFn},
+	"map":            Intrinsic{op: mapFn},
+	"eval":           Intrinsic{op: evalFn},
+	"set-car!":       Intrinsic{op: setCar},
+	"set-cdr!":       Intrinsic{op: setCdr},
+	"error":          Intrinsic{op: signalError},
+	"begin":          Intrinsic{op: begin},
+	"case":           Intrinsic{op: caseForm},
+	"prune-env!":     Intrinsic{op: pruneEnv},
+	"env-stats":      Intrinsic{op: envStats},
+	"queue":          Intrinsic{op: makeQueue},
+	"push-front!":    Intrinsic{op: pushFront},
+	"push-back!":     Intrinsic{op: pushBack},
+	"pop-front!":     Intrinsic{op: popFront},
+	"pop-back!":      Intrinsic{op: popBack},
+	"queue-empty?":   Intrinsic{op: isQueueEmpty},
+	"future":         Intrinsic{op: makeFuture},
+	"deref":          Intrinsic{op: deref},
+	"realized?":      Intrinsic{op: isRealized},
+	"make-error":     Intrinsic{op: makeError},
+	"error-message":  Intrinsic{op: errorMessage},
+	"error-kind":     Intrinsic{op: errorKind},
+	"error-data":     Intrinsic{op: errorData},
+	"error?":         Intrinsic{op: isError},
+	"raise":          Intrinsic{op: raise},
+	"send!":          Intrinsic{op: send},
+	"recv!":          Intrinsic{op: recv},
+	"chan":           Intrinsic{op: makeChan},
+	"close!":         Intrinsic{op: closeChan},
+	"select":         Intrinsic{op: selectForm},
+	"cancel!":        Intrinsic{op: cancelContext},
+	"done?":          Intrinsic{op: isDone},
+	"re-compile":     Intrinsic{op: reCompile},
+	"re-match?":      Intrinsic{op: reMatch},
+	"re-find":        Intrinsic{op: reFind},
+	"re-find-all":    Intrinsic{op: reFindAll},
+	"re-replace":     Intrinsic{op: reReplace},
+	"close-port":     Intrinsic{op: closePort},
+	"read-char":      Intrinsic{op: readChar},
+	"write-string":   Intrinsic{op: writeString},
+	"eof-object?":    Intrinsic{op: isEOFObject},
+	"time->string":   Intrinsic{op: timeToString},
+	"string->time":   Intrinsic{op: stringToTime},
+	"time-add":       Intrinsic{op: timeAdd},
+	"time-diff":      Intrinsic{op: timeDiff},
+	"time-parts":     Intrinsic{op: timeParts},
+	"format-date":    Intrinsic{op: formatDate},
+	"parse-date":     Intrinsic{op: parseDate},
+	"format-number":  Intrinsic{op: formatNumber},
+	"decimal":        Intrinsic{op: makeDecimal},
+	"decimal-round":  Intrinsic{op: decimalRound},
+	"decimal?":       Intrinsic{op: isDecimal},
+	"hash-get":       Intrinsic{op: hashGet},
+	"hash-set!":      Intrinsic{op: hashSet},
+	"hash-del!":      Intrinsic{op: hashDel},
+	"hash-keys":      Intrinsic{op: hashKeys},
+	"hash?":          Intrinsic{op: isHash},
+	"nil?":           Intrinsic{op: isNil},
+	"symbol?":        Intrinsic{op: isSymbol},
+	"symbol->string": Intrinsic{op: symbolToString},
+	"put":            Intrinsic{op: put},
+	"get":            Intrinsic{op: get},
+	"symbol-plist":   Intrinsic{op: symbolPlist},
+	"string->symbol": Intrinsic{op: stringToSymbol},
+	"string?":        Intrinsic{op: isString},
+	"char?":          Intrinsic{op: isChar},
+	"boolean?":       Intrinsic{op: isBoolean},
+	"keyword?":       Intrinsic{op: isKeyword},
+	"char->int":      Intrinsic{op: charToInt},
+	"int->char":      Intrinsic{op: intToChar},
+	"num?":           Intrinsic{op: isNum},
+	"fixnum?":        Intrinsic{op: isFixnum},
+	"integer?":       Intrinsic{op: isInteger},
+	"rational?":      Intrinsic{op: isRational},
+	"float?":         Intrinsic{op: isFlonum},
+	"list?":          Intrinsic{op: isList},
+	"pair?":          Intrinsic{op: isPair},
+	"lambda?":        Intrinsic{op: isLambda},
+	"intrinsic?":     Intrinsic{op: isIntrinsic},
+
+	// strings count runes, the byte variants count bytes
+	"string-length":      Intrinsic{op: stringLength},
+	"substring":          Intrinsic{op: substring},
+	"string-ref":         Intrinsic{op: stringRef},
+	"string-upcase":      Intrinsic{op: stringUpcase},
+	"string-downcase":    Intrinsic{op: stringDowncase},
+	"string-byte-length": Intrinsic{op: stringByteLength},
+	"string-byte-ref":    Intrinsic{op: stringByteRef},
+	"byte-substring":     Intrinsic{op: byteSubstring},
+
+	"the-environment":  Intrinsic{op: theEnvironment},
+	"unquote-splicing": Intrinsic{op: unquoteOutside},
+	"close-generator!": Intrinsic{op: closeGenerator},
+	"timeout-context":  Intrinsic{op: timeoutContext},
+
+	// instrumentation
+	"slow-call-threshold!": Intrinsic{op: setSlowCallThreshold}}
+
+// turns a "quoted" token back into the string it represents
+func ParseString(s string) LispObject {
+	buf := ""
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' {
+			buf += body[i : i+1]
+			continue
+		}
+		i++
+		switch body[i] {
+		case 'n':
+			buf += "\n"
+		case 't':
+			buf += "\t"
+		case '"', '\\':
+			buf += string(body[i])
+		default:
+			panic("unknown escape sequence \\" + string(body[i]))
+		}
+	}
+	return lispString(buf)
+}
+
+// #\a and #\newline style character literals
+func ParseChar(s string) LispObject {
+	body := s[2:]
+	if r, ok := charNames[body]; ok {
+		return lispChar(r)
+	}
+	r, size := utf8.DecodeRuneInString(body)
+	if size != len(body) {
+		panic("unknown character name " + s)
+	}
+	return lispChar(r)
+}
 
 func ParseAtom(s string) LispObject {
+	if strings.HasPrefix(s, `"`) {
+		return ParseString(s)
+	}
+	if strings.HasPrefix(s, `#\`) && len(s) > 2 {
+		return ParseChar(s)
+	}
+	if strings.HasPrefix(s, ":") && len(s) > 1 {
+		return keyword(s[1:])
+	}
+	switch s {
+	case "#t":
+		return True
+	case "#f":
+		return False
+	}
 	if num, err := strconv.ParseInt(s, 10, 0); err == nil {
 		return fixnum(num)
 	}
-	return symbol(s)
+	if num, ok := parseBignum(s); ok {
+		return num
+	}
+	if num, ok := parseRatnum(s); ok {
+		return num
+	}
+	if num, ok := parseFlonum(s); ok {
+		return num
+	}
+	if strings.HasPrefix(s, "#d") {
+		if num, ok := parseDecimal(s[2:]); ok {
+			return num
+		}
+	}
+	return intern(s)
 
 }
 func ParseList(tokens []string) (LispObject, []string) {
-	if tokens[0] == ")" {
-		return Nil, tokens[1:]
-	}
-	retList := list{}
+	items := []LispObject{}
 	for {
 		switch tokens[0] {
 		case ")":
-			return retList, tokens[1:]
-		case "(":
-			obj, t := ParseList(tokens[1:])
+			return list(items...), tokens[1:]
+		case "(", "'", "`", ",", ",@":
+			obj, t := ParseTree(tokens)
 			tokens = t
-			retList = append(retList, obj)
+			items = append(items, obj)
+		case ".":
+			// (a b . c) has exactly one datum between the dot and the paren
+			if len(items) == 0 {
+				panic("bad dotted list")
+			}
+			tail, t := ParseTree(tokens[1:])
+			if len(t) == 0 || t[0] != ")" {
+				panic("bad dotted list")
+			}
+			return listWithTail(items, tail), t[1:]
 		default:
-			retList = append(retList, ParseAtom(tokens[0]))
+			items = append(items, ParseAtom(tokens[0]))
 			tokens = tokens[1:]
 		}
 	}
-	return Nil, nil
 }
-func ParseTree(tokens []string) (obj LispObject) {
+
+// the forms the reader's shorthand prefixes expand to
+var readerMacros = map[string]string{
+	"'":  "quote",
+	"`":  "quasiquote",
+	",":  "unquote",
+	",@": "unquote-splicing"}
+
+// reads one datum from the front of tokens, returning it and the tokens left
+func ParseTree(tokens []string) (LispObject, []string) {
 	switch tok := tokens[0]; tok {
 	case "(":
-		obj, _ := ParseList(tokens[1:])
-		return obj
+		return ParseList(tokens[1:])
+	case ")":
+		panic("unexpected )")
+	case "'", "`", ",", ",@":
+		// 'x is (quote x), and the rest are shorthand for quasiquote templates
+		if len(tokens) == 1 {
+			panic("expected data after " + tok)
+		}
+		obj, rest := ParseTree(tokens[1:])
+		return list(intern(readerMacros[tok]), obj), rest
 	default:
-		return ParseAtom(tok)
+		return ParseAtom(tok), tokens[1:]
 	}
-	return Nil
 }
 
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// splits input into parens, atoms and string literals. string literals keep
+// their quotes and escapes so ParseAtom can tell them apart from symbols
+func Tokenize(input string) []string {
+	tokens := []string{}
+	for i := 0; i < len(input); {
+		switch c := input[i]; {
+		case isSpace(c):
+			i++
+		case c == ';':
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case c == '(' || c == ')' || c == '\'' || c == '`':
+			tokens = append(tokens, string(c))
+			i++
+		case c == ',':
+			if strings.HasPrefix(input[i:], ",@") {
+				tokens = append(tokens, ",@")
+				i += 2
+			} else {
+				tokens = append(tokens, ",")
+				i++
+			}
+		case c == '"':
+			j := i + 1
+			for ; j < len(input) && input[j] != '"'; j++ {
+				if input[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(input) {
+				panic("unterminated string")
+			}
+			tokens = append(tokens, input[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			if strings.HasPrefix(input[i:], `#\`) && i+2 < len(input) {
+				// the character after #\ is always part of the token, even ( or "
+				_, size := utf8.DecodeRuneInString(input[i+2:])
+				j = i + 2 + size
+			}
+			for j < len(input) && !isSpace(input[j]) && !strings.ContainsRune("()\";`,", rune(input[j])) {
+				j++
+			}
+			tokens = append(tokens, input[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+// true while input has unclosed parens or an unterminated string, so the repl
+// knows to keep reading lines
+func incomplete(input string) bool {
+	depth := 0
+	inString := false
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case inString && c == '\\':
+			i++
+		case !inString && c == '#' && strings.HasPrefix(input[i:], `#\`):
+			i += 2
+		case c == '"':
+			inString = !inString
+		case !inString && c == ';':
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case !inString && c == '(':
+			depth++
+		case !inString && c == ')':
+			depth--
+		}
+	}
+	return inString || depth > 0
+}
+
+// reads every top level form in input, for loading whole files
+func ReadAll(input string) []LispObject {
+	tokens := Tokenize(input)
+	forms := []LispObject{}
+	for len(tokens) > 0 {
+		var obj LispObject
+		obj, tokens = ParseTree(tokens)
+		forms = append(forms, obj)
+	}
+	return forms
+}
+
+// where print writes, replaceable in tests
+var printOut io.Writer = os.Stdout
+
+// warnings about loaded code go to stderr so they don't mix with results
+var warnOut io.Writer = os.Stderr
+
+// evaluates every form in src, then warns about any function it defined that
+// calls a name that still isn't bound
+func loadSource(src string, env Environment) LispObject {
+	var result LispObject = Nil
+	defined := []*symbol{}
+	for _, form := range ReadAll(src) {
+		result = form.Eval(env)
+		if isDefinition(form) {
+			defined = append(defined, result.(*symbol))
+		}
+	}
+	for _, name := range defined {
+		if fn, ok := env.Get(name.name).(lambda); ok {
+			if missing := undefinedCalls(fn, &env); len(missing) > 0 {
+				fmt.Fprintf(warnOut, "warning: %v calls undefined %v\n", name.name, strings.Join(missing, ", "))
+			}
+		}
+	}
+	return result
+}
+
+// (load "file.lisp") evaluates the file, returning the value of its last form
+func load(rawlist []LispObject, env Environment) LispObject {
+	path := rawlist[1].Eval(env).(lispString)
+	src, err := os.ReadFile(string(path))
+	if err != nil {
+		panic(err.Error())
+	}
+	return loadSource(string(src), env)
+}
+
+// reads the first form in input. input with no form at all, only whitespace
+// and comments, reads as ()
 func Read(input string) (obj LispObject) {
-	newstr := strings.Replace(input, "(", " ( ", -1)
-	newstr = strings.Replace(newstr, ")", " ) ", -1)
-	tokens := strings.Fields(newstr)
+	tokens := Tokenize(input)
 
 	if len(tokens) == 0 {
-		panic("expected data")
+		return Nil
 	}
-	return ParseTree(tokens)
+	obj, _ = ParseTree(tokens)
+	return obj
 }
 
-func main() {
-	buffer := bufio.NewReader(os.Stdin)
+// the top level environment with the core intrinsics and every extension in
+// defaultExtensions bound
+func newGlobalEnv() Environment {
 	globalEnv := newEnv(50)
 	for name, op := range IntrinsicList {
 		globalEnv.Put(name, op)
 	}
+	if err := useExtensions(globalEnv, defaultExtensions...); err != nil {
+		panic(err.Error())
+	}
+	return globalEnv
+}
+
+func repl(globalEnv Environment) {
+	buffer := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Print("lisp.go>")
-		line, _ := buffer.ReadString(byte('\n'))
-		for strings.Count(line, "(") != strings.Count(line, ")") {
-			tmpline, _ := buffer.ReadString(byte('\n'))
+		line, err := buffer.ReadString(byte('\n'))
+		for err == nil && incomplete(line) {
+			var tmpline string
+			tmpline, err = buffer.ReadString(byte('\n'))
 			line += tmpline
 		}
-		tree := Read(line)
-		fmt.Printf("got %v\n", tree.Print())
-		fmt.Printf("-> %v\n", tree.Eval(globalEnv).Print())
+		// blank and comment-only lines have no forms, so go straight back to
+		// the prompt
+		for _, tree := range ReadAll(line) {
+			if isDefinition(tree) {
+				fmt.Printf("=> %v\n", tree.Eval(globalEnv).Print())
+				continue
+			}
+			fmt.Printf("got %v\n", tree.Print())
+			fmt.Printf("-> %v\n", tree.Eval(globalEnv).Print())
+		}
+		if err != nil {
+			fmt.Println()
+			return
+		}
+	}
+}
+
+func main() {
+	jsonrpc := flag.Bool("jsonrpc", false, "speak JSON-RPC over stdin/stdout instead of running the repl, sending printed output to stderr")
+	callgraph := flag.String("callgraph", "", "print the call graph of the functions defined in `file` as Graphviz DOT")
+	config := flag.String("config", "", "evaluate the config `file` without file or terminal access and print its value as JSON")
+	generate := flag.String("generate", "", "run the generator program `file`, writing emitted text to stdout and output files under the current directory")
+	testDir := flag.String("test", "", "run the tests defined with deftest in the .lisp files in `dir`")
+	tutorial := flag.Bool("tutorial", false, "walk through the built-in lessons instead of running the repl")
+	slowCalls := flag.Int("slow-calls", 0, "log function applications taking at least `ms` milliseconds to stderr")
+	flag.Parse()
+	slowCallThreshold.Store(int64(*slowCalls) * int64(time.Millisecond))
+
+	if flag.Arg(0) == "exercise" {
+		if err := runExercise(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *callgraph != "" {
+		src, err := os.ReadFile(*callgraph)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(dotGraph(callGraph(ReadAll(string(src)))))
+		return
+	}
+
+	if *testDir != "" {
+		if err := runTestSuite(*testDir, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *tutorial {
+		if err := runTutorial(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *generate != "" {
+		src, err := os.ReadFile(*generate)
+		if err == nil {
+			err = newTextGenerator(os.Stdout, ".").Run(string(src))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", *generate, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *config != "" {
+		src, err := os.ReadFile(*config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		val, err := evalString(string(src), configEnv())
+		if err == nil {
+			var out []byte
+			if out, err = marshalJSON(val); err == nil {
+				fmt.Println(string(out))
+				return
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%v: %v\n", *config, err)
+		os.Exit(1)
+	}
+
+	globalEnv := newGlobalEnv()
+	if *jsonrpc {
+		serveJSONRPC(os.Stdin, os.Stdout, os.Stderr, globalEnv)
+		return
 	}
+	repl(globalEnv)
 }
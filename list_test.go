@@ -1,8 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 var nilEnv Environment = newEnv(0)
@@ -34,7 +43,7 @@ func TestEvalFixnum(t *testing.T) {
 
 func TestEvalSymbol(t *testing.T) {
 	testEnv := nilEnv.FromParent([]string{"x"}, []LispObject{fixnum(123)})
-	n := symbol("x").Eval(testEnv)
+	n := intern("x").Eval(testEnv)
 	switch v := n.(type) {
 	case fixnum:
 		{
@@ -50,7 +59,7 @@ func TestEvalSymbol(t *testing.T) {
 
 func TestEvalLambda(t *testing.T) {
 	testLambda := lambda{
-		fn:      symbol("x"),
+		fn:      intern("x"),
 		arglist: []string{"x"}}
 
 	n := testLambda.Eval(nilEnv)
@@ -63,9 +72,9 @@ func TestEvalLambda(t *testing.T) {
 }
 func TestEvalList(t *testing.T) {
 	testLambda := lambda{
-		fn:      symbol("x"),
+		fn:      intern("x"),
 		arglist: []string{"x"}}
-	testList := list{testLambda, fixnum(5)}
+	testList := list(testLambda, fixnum(5))
 
 	n := testList.Eval(nilEnv)
 	switch v := n.(type) {
@@ -86,8 +95,8 @@ func TestParseList(t *testing.T) {
 		[]string{"+", "1", "2", "3", ")"}}
 	expected := []LispObject{
 		Nil,
-		list{Nil},
-		list{symbol("+"), fixnum(1), fixnum(2), fixnum(3)}}
+		list(Nil),
+		list(intern("+"), fixnum(1), fixnum(2), fixnum(3))}
 	for i := range input {
 		obj, _ := ParseList(input[i])
 		if !reflect.DeepEqual(obj, expected[i]) {
@@ -96,3 +105,1429 @@ func TestParseList(t *testing.T) {
 		}
 	}
 }
+
+func TestBlankInput(t *testing.T) {
+	for _, input := range []string{"", "  \t\n", "; just a comment", " ; two\n; lines\n"} {
+		if obj := Read(input); obj != Nil {
+			t.Errorf("expected %q to read as (), got %v", input, obj.Print())
+		}
+		if forms := ReadAll(input); len(forms) != 0 {
+			t.Errorf("expected %q to have no forms, got %v", input, len(forms))
+		}
+	}
+	forms := ReadAll("; sum\n(+ 1 2) ; three\n")
+	if len(forms) != 1 || forms[0].Print() != "(+ 1 2)" {
+		t.Errorf("expected comments to be skipped, got %v", forms)
+	}
+	if !incomplete("(+ 1 ; )\n") {
+		t.Errorf("expected a paren in a comment not to close the form")
+	}
+}
+
+func TestDefReturnsSymbol(t *testing.T) {
+	env := newEnv(1)
+	env.Put("def", Intrinsic{op: def})
+	tree := Read("(def id (x) x)")
+	if !isDefinition(tree) {
+		t.Errorf("expected %v to be a definition", tree.Print())
+	}
+	n := tree.Eval(env)
+	if n != intern("id") {
+		t.Errorf("Expected (def id (x) x) -> id, got %v instead", n)
+	}
+	if _, ok := env.Get("id").(lambda); !ok {
+		t.Errorf("Expected id to be bound to a lambda, got %v instead", env.Get("id"))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	env := newEnv(3)
+	env.Put("diff", Intrinsic{op: diff})
+	env.Put("quote", Intrinsic{op: quote})
+	env.Put("begin", Intrinsic{op: begin})
+	runEvalCases(t, env, []evalCase{
+		{"(diff (quote (1 (2 3))) (quote (1 (2 3))))", "()"},
+		{"(diff (quote (1 (2 3))) (quote (1 (2 4))))", "(((1 1) 3 4))"},
+		{"(diff 1 2)", "((() 1 2))"},
+		{"(diff (quote (1 2)) (quote (1 2 3)))", "((() (1 2) (1 2 3)))"}})
+}
+
+func TestWalk(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{"(postwalk length (quote (1 (2 3) 4)))", "3"},
+		{"(postwalk (lambda (x) (num? x)) (quote (1 (2))))", "#f"},
+		{"(prewalk (lambda (x) x) (quote (1 (2 3))))", "(1 (2 3))"},
+		{"(prewalk (lambda (x) x) 5)", "5"}})
+}
+
+func TestSexpSelect(t *testing.T) {
+	env := newGlobalEnv()
+	config := "(quote (config (server (port 80)) (server (port 81) (host a))))"
+	runEvalCases(t, env, []evalCase{
+		{"(sexp-select " + config + " (quote (server port)))", "((port 80) (port 81))"},
+		{"(sexp-select " + config + " (quote (server *)))", "(server (port 80) server (port 81) (host a))"},
+		{"(sexp-select " + config + " (quote (** host)))", "((host a))"},
+		{"(sexp-select " + config + " (quote (client)))", "()"}})
+}
+
+func TestCompare(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{"(< 1 2 3)", "#t"},
+		{"(< 1 3 2)", "#f"},
+		{"(>= 3 3 1)", "#t"},
+		{"(= 2 2 2)", "#t"},
+		{"(compare 1 2)", "-1"},
+		{"(compare 2 2)", "0"},
+		{"(compare (quote b) (quote a))", "1"}})
+}
+
+func TestBooleans(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{"(not ())", "#t"},
+		{"(not 5)", "#f"},
+		{"(boolean 5)", "#t"},
+		{"(boolean ())", "#f"},
+		{"(and 1 ())", "#f"},
+		{"(or 1 ())", "#t"},
+		{"(if (< 1 2) 1 2)", "1"},
+		{"(if (nil? 1) 1 2)", "2"},
+		{"(let ((x (quote (1 2)))) (eq? x x))", "#t"},
+		{"#f", "#f"},
+		{"(if #f 1 2)", "2"},
+		{"(boolean? #t)", "#t"},
+		{"(not #f)", "#t"}})
+}
+
+func TestProgress(t *testing.T) {
+	env := newGlobalEnv()
+	var buf bytes.Buffer
+	progressOut = &buf
+	defer func() { progressOut = os.Stderr }()
+
+	obj := Read("(with-progress 4 (lambda () (+ (progress! 1) (progress! 3))))").Eval(env)
+	if !reflect.DeepEqual(obj, fixnum(5)) {
+		t.Errorf("expected with-progress to return the thunk's value 5, got %v", obj.Print())
+	}
+	if !strings.HasSuffix(buf.String(), "] 4/4\n") {
+		t.Errorf("expected a completed bar, got %q", buf.String())
+	}
+}
+
+func TestTerminalControl(t *testing.T) {
+	env := newGlobalEnv()
+	var buf bytes.Buffer
+	termOut = &buf
+	defer func() { termOut = os.Stdout }()
+
+	Read("(move-cursor 3 7)").Eval(env)
+	if buf.String() != "\x1b[3;7H" {
+		t.Errorf("expected cursor escape for row 3 col 7, got %q", buf.String())
+	}
+	if width, ok := Read("(terminal-width)").Eval(env).(fixnum); !ok || width <= 0 {
+		t.Errorf("expected a positive terminal width, got %v", width)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	env := newGlobalEnv()
+	cases := []evalCase{
+		{`"hello world"`, `"hello world"`},
+		{`"a \"quoted\" (paren)\n"`, `"a \"quoted\" (paren)\n"`},
+		{`(string? "x")`, "#t"},
+		{`(equal? "ab" "ab")`, "#t"},
+		{`(compare "a" "b")`, "-1"}}
+	runEvalCases(t, env, cases)
+	for _, c := range cases {
+		if again := Read(c.want).Print(); again != c.want {
+			t.Errorf("expected %v to read back as itself, got %v", c.want, again)
+		}
+	}
+	if !incomplete(`(print "(")`[:9]) || incomplete(`(print "(")`) {
+		t.Errorf("expected parens inside strings not to count towards balance")
+	}
+}
+
+func TestFloats(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{"(+ 1.5 2.25)", "3.75"},
+		{"(* 2 1.5)", "3.0"},
+		{"(/ 1 2.0)", "0.5"},
+		{"1e3", "1000.0"},
+		{"(< 1 1.5 2)", "#t"},
+		{"(= 2 2.0)", "#t"},
+		{"(float? 2.0)", "#t"},
+		{"(num? 2.0)", "#t"},
+		{"(equal? 2 2.0)", "#f"},
+		{"(quote inf)", "inf"}})
+	if s := flonum(3).Print(); s != "3.0" {
+		t.Errorf("expected 3.0 to print as 3.0, got %v", s)
+	}
+}
+
+func TestBignums(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{"(* 9223372036854775807 2)", "18446744073709551614"},
+		{"(- (* 9223372036854775807 2) 9223372036854775807)", "9223372036854775807"},
+		{"(< 9223372036854775807 100000000000000000000)", "#t"},
+		{"(equal? 100000000000000000000 100000000000000000000)", "#t"},
+		{"(integer? 100000000000000000000)", "#t"},
+		{"(+ 100000000000000000000 0.5)", "1e+20"}})
+}
+
+func TestRationals(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{"(/ 1 3)", "1/3"},
+		{"(/ 4 2)", "2"},
+		{"(+ 1/3 2/3)", "1"},
+		{"(* 2/4 3)", "3/2"},
+		{"(< 1/3 0.5 1/2 1)", "#f"},
+		{"(+ 1/2 0.25)", "0.75"},
+		{"(rational? 1/3)", "#t"},
+		{"(integer? 1/3)", "#f"},
+		{"(quote /)", "/"}})
+}
+
+func TestChars(t *testing.T) {
+	env := newGlobalEnv()
+	cases := []evalCase{
+		{`#\a`, `#\a`},
+		{`#\newline`, `#\newline`},
+		{`#\(`, `#\(`},
+		{`#\λ`, `#\λ`},
+		{`(char->int #\a)`, "97"},
+		{`(int->char 97)`, `#\a`},
+		{`(char? #\space)`, "#t"},
+		{`(char? "a")`, "#f"},
+		{`(compare #\a #\b)`, "-1"}}
+	runEvalCases(t, env, cases)
+	for _, c := range cases {
+		if again := Read(c.want).Print(); again != c.want {
+			t.Errorf("expected %v to read back as itself, got %v", c.want, again)
+		}
+	}
+	if incomplete(`(char->int #\()`) {
+		t.Errorf(`expected #\( not to count as an open paren`)
+	}
+}
+
+func TestHash(t *testing.T) {
+	env := newGlobalEnv()
+	Read("(set! h (make-hash))").Eval(env)
+	Read(`(hash-set! h "a" 1)`).Eval(env)
+	Read("(hash-set! h (quote a) 2)").Eval(env)
+	Read("(hash-set! h (quote (1 2)) 3)").Eval(env)
+	Read(`(hash-set! h "a" 4)`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(hash-get h "a")`, "4"},
+		{"(hash-get h (quote a))", "2"},
+		{"(hash-get h (list 1 2))", "3"},
+		{"(hash-get h 5)", "()"},
+		{"(hash-get h 5 0)", "0"},
+		{"(length h)", "3"},
+		{"(hash-keys h)", `("a" (1 2) a)`},
+		{"(hash? h)", "#t"},
+		{"(hash? 1)", "#f"},
+		{"(hash-set! h #d1.0 5) (hash-get h #d1.00)", "5"},
+		{"(hash-set! h (list #d1.0) 6) (hash-set! h (list #d1.00) 7) (hash-get h (list #d1.0))", "7"},
+		{"(length h)", "5"}})
+	Read(`(hash-del! h "a")`).Eval(env)
+	if obj := Read(`(hash-get h "a")`).Eval(env); obj != Nil {
+		t.Errorf("expected deleted key to be missing, got %v", obj.Print())
+	}
+}
+
+func TestCons(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{"(cons 1 2)", "(1 . 2)"},
+		{"(cons 1 (quote (2 3)))", "(1 2 3)"},
+		{"(quote (1 2 . 3))", "(1 2 . 3)"},
+		{"(car (quote (1 . 2)))", "1"},
+		{"(cdr (quote (1 . 2)))", "2"},
+		{"(cdr (quote (1)))", "()"},
+		{"(pair? (cons 1 2))", "#t"},
+		{"(list? (cons 1 2))", "#f"},
+		{"(list? ())", "#t"},
+		{"(equal? (cons 1 2) (quote (1 . 2)))", "#t"},
+		{"(let ((x (quote (1 2)))) (eq? (cdr x) (cdr x)))", "#t"},
+		{"(eq? (list 1 2) (list 1 2))", "#f"}})
+	printed := map[LispObject]string{
+		list(fixnum(1), fixnum(2)):                                  "(1 2)",
+		listWithTail([]LispObject{fixnum(1), fixnum(2)}, fixnum(3)): "(1 2 . 3)",
+		list(list(fixnum(1)), Nil):                                  "((1) ())"}
+	for obj, s := range printed {
+		if obj.Print() != s {
+			t.Errorf("expected %v to print as %v", obj.Print(), s)
+		}
+	}
+}
+
+func TestJSONRPC(t *testing.T) {
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eval","params":{"code":"(+ 1 2)"}}
+{"jsonrpc":"2.0","id":2,"method":"eval","params":{"code":"(car 1)"}}
+{"jsonrpc":"2.0","method":"eval","params":{"code":"(def id (x) x)"}}
+{"jsonrpc":"2.0","id":3,"method":"complete","params":{"prefix":"hash-s"}}
+{"jsonrpc":"2.0","id":4,"method":"doc","params":{"name":"id"}}
+{"jsonrpc":"2.0","id":5,"method":"frobnicate"}
+{"jsonrpc":"2.0","id":6,"method":"eval","params":{"code":"(begin (print 1) (write-string \"x\") (clear-screen) 2)"}}
+{"jsonrpc":"2.0","id":7,"method":"interrupt"}
+`)
+	var out, logs bytes.Buffer
+	serveJSONRPC(in, &out, &logs, newGlobalEnv())
+	expected := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"value":"3"}}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32000,"message":"expected a pair, got 1"}}`,
+		`{"jsonrpc":"2.0","id":3,"result":["hash-set!"]}`,
+		`{"jsonrpc":"2.0","id":4,"result":{"name":"id","kind":"lambda","args":["x"]}}`,
+		`{"jsonrpc":"2.0","id":5,"error":{"code":-32601,"message":"unknown method frobnicate"}}`,
+		`{"jsonrpc":"2.0","id":6,"result":{"value":"2"}}`,
+		`{"jsonrpc":"2.0","id":7,"error":{"code":-32601,"message":"interrupt is not supported"}}`}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected responses\n%v\ngot\n%v", strings.Join(expected, "\n"), out.String())
+	}
+	if logs.String() != "1\nx\x1b[2J\x1b[H" {
+		t.Errorf("expected printed output to go to the logs, got %q", logs.String())
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{":port", ":port"},
+		{"(keyword? :port)", "#t"},
+		{"(keyword? (quote port))", "#f"},
+		{"(equal? :port (quote port))", "#f"},
+		{"(eq? :port :port)", "#t"},
+		{"(compare :a :b)", "-1"},
+		{"(quote (:host 1))", "(:host 1)"}})
+}
+
+func TestDotGraph(t *testing.T) {
+	env := newGlobalEnv()
+	obj := Read(`(dot-graph (quote ((a b) ("b c" . 1))))`).Eval(env)
+	expected := lispString("digraph {\n  \"a\" -> \"b\";\n  \"b c\" -> \"1\";\n}\n")
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("expected %v, got %v", expected.Print(), obj.Print())
+	}
+
+	src := `(def even (n) (if (= n 0) #t (odd (- n 1))))
+(def odd (n) (if (= n 0) #f (even (- n 1))))
+(def main () (print (even 10)))`
+	edges := callGraph(ReadAll(src))
+	expectedEdges := []edge{{"even", "odd"}, {"odd", "even"}, {"main", "even"}}
+	if !reflect.DeepEqual(edges, expectedEdges) {
+		t.Errorf("expected call graph %v, got %v", expectedEdges, edges)
+	}
+}
+
+func TestDefstruct(t *testing.T) {
+	env := newGlobalEnv()
+	Read("(defstruct point x y)").Eval(env)
+	Read("(set! p (make-point 1 (+ 1 1)))").Eval(env)
+	Read("(set-point-x! p 5)").Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{"(point-x p)", "5"},
+		{"(point-y p)", "2"},
+		{"(point? p)", "#t"},
+		{"(point? 1)", "#f"},
+		{"(equal? (make-point 1 2) (make-point 1 2))", "#t"},
+		{"(eq? (make-point 1 2) (make-point 1 2))", "#f"},
+		{"(eq? p p)", "#t"}})
+	if s := Read("p").Eval(env).Print(); s != "<point 5 2>" {
+		t.Errorf("expected p to print as <point 5 2>, got %v", s)
+	}
+}
+
+func TestUses(t *testing.T) {
+	env := newGlobalEnv()
+	var warnings bytes.Buffer
+	warnOut = &warnings
+	defer func() { warnOut = os.Stderr }()
+
+	loadSource(`(def double (x) (* x 2))
+(def quad (x) (double (double x)))
+(def shout (x) (let ((y (double x))) (louder y)))
+(def count-to (n) (let loop ((i 0)) (if (< i n) (loop (+ i 1)) i)))
+(def template (x) `+"`(double ,(double x))"+`)
+(def doubles (l) (generator (let loop ((l l)) (yield (double (car l))) (loop (cdr l)))))
+(def mk () (lambda (g) (g 1)))
+(def twice (f) (lambda (x . more) (f (f x))))
+(def tally (fs) (do ((fs fs (cdr fs)) (step car) (n 0 (+ n 1))) ((nil? fs) (step n)) (double n)))
+(def keyed (&key (hook double)) (hook 1))`, env)
+	runEvalCases(t, env, []evalCase{
+		{"(uses (quote quad))", "(double)"},
+		{"(uses (quote double))", "()"},
+		{"(used-by (quote double))", "(doubles quad shout tally template)"}})
+	if warnings.String() != "warning: shout calls undefined louder\n" {
+		t.Errorf("expected a warning about louder, got %q", warnings.String())
+	}
+}
+
+func TestInternedSymbols(t *testing.T) {
+	env := newGlobalEnv()
+	if Read("foo") != Read("(quote foo)").Eval(env) {
+		t.Errorf("expected every read of foo to be the same symbol")
+	}
+	runEvalCases(t, env, []evalCase{
+		{"(eq? (quote foo) (quote foo))", "#t"},
+		{`(eq? (string->symbol "foo") (quote foo))`, "#t"},
+		{"(symbol->string (quote foo))", `"foo"`},
+		{`(string->symbol (symbol->string (quote foo)))`, "foo"}})
+}
+
+func TestStreams(t *testing.T) {
+	env := newGlobalEnv()
+	Read("(def ints-from (n) (stream-cons n (ints-from (+ n 1))))").Eval(env)
+	Read("(set! p (delay (set! forced (+ 1 2))))").Eval(env)
+	if env.Get("forced") != Nil {
+		t.Errorf("expected delay not to evaluate its expression")
+	}
+	runEvalCases(t, env, []evalCase{
+		{"(force p)", "3"},
+		{"(force p)", "3"},
+		{"(force 5)", "5"},
+		{"(promise? p)", "#t"},
+		{"(stream-car (stream-cdr (ints-from 1)))", "2"},
+		{"(stream-take (ints-from 1) 3)", "(1 2 3)"},
+		{"(length (stream-take (ints-from 1) 1000))", "1000"},
+		{"(set! forces 0) (def counted (n) (stream-cons n (begin (set! forces (+ forces 1)) (counted (+ n 1)))))", "counted"},
+		{"(stream-take (counted 1) 2) forces", "1"},
+		{"(stream-take (counted 1) 0) forces", "1"},
+		{"(stream-take (ints-from 1) (quote a))", "error: stream-take needs a count, got a"}})
+	if !reflect.DeepEqual(env.Get("forced"), fixnum(3)) {
+		t.Errorf("expected force to evaluate the delayed expression")
+	}
+}
+
+func TestAlists(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! al (acons (quote a) 1 (quote (("b" . 2) (a . 3)))))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(assoc "b" al)`, `("b" . 2)`},
+		{"(assq (quote a) al)", "(a . 1)"},
+		{"(assq (quote c) al)", "#f"},
+		{"(alist-get (quote a) al)", "1"},
+		{"(alist-get (quote c) al 0)", "0"},
+		{"(hash-get (alist->hash al) (quote a))", "1"},
+		{"(length (alist->hash al))", "2"}})
+}
+
+func TestExtensions(t *testing.T) {
+	env := newEnv(0)
+	for name, op := range IntrinsicList {
+		env.Put(name, op)
+	}
+	if env.Get("load") != Nil {
+		t.Errorf("expected load to be left out of the core intrinsics")
+	}
+	if err := useExtensions(env, fileIntrinsics); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := env.Get("load").(Intrinsic); !ok {
+		t.Errorf("expected useExtensions to bind load, got %v", env.Get("load").Print())
+	}
+}
+
+func TestSymbolPlists(t *testing.T) {
+	env := newGlobalEnv()
+	Read("(put (quote plist-x) :color (quote red))").Eval(env)
+	Read("(put (quote plist-x) :size 3)").Eval(env)
+	Read("(put (quote plist-x) :color (quote blue))").Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{"(get (quote plist-x) :color)", "blue"},
+		{"(get (quote plist-x) :size)", "3"},
+		{"(get (quote plist-x) :weight)", "()"},
+		{"(get (string->symbol \"plist-x\") :size)", "3"},
+		{"(symbol-plist (quote plist-x))", "(:color blue :size 3)"}})
+}
+
+func TestTimes(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! t1 (string->time "2026-10-16T09:30:00Z"))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(time->string (time-add t1 90))`, `"2026-10-16T09:31:30Z"`},
+		{`(time->string t1 "2006-01-02")`, `"2026-10-16"`},
+		{`(time-diff (time-add t1 1.5) t1)`, "1.5"},
+		{`(time-parts t1)`, "(:year 2026 :month 10 :day 16 :hour 9 :minute 30 :second 0 :weekday 5)"},
+		{`(compare t1 (time-add t1 1))`, "-1"},
+		{`(equal? t1 (string->time "2026-10-16" "2006-01-02"))`, "#f"}})
+}
+
+func TestGoCall(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{`(go-call "strings.ToUpper" "abc")`, `"ABC"`},
+		{`(go-call "strings.Split" "a,b" ",")`, `("a" "b")`},
+		{`(go-call "strings.Join" (quote ("a" "b")) "-")`, `"a-b"`},
+		{`(go-call "strconv.Atoi" "42")`, "42"},
+		{`(go-call "math.Sqrt" 16)`, "4.0"}})
+	for _, input := range []string{`(go-call "os.Exit" 1)`, `(go-call "strconv.Atoi" "x")`} {
+		if _, err := evalString(input, env); err == nil {
+			t.Errorf("expected %v to fail", input)
+		}
+	}
+}
+
+func TestPorts(t *testing.T) {
+	env := newGlobalEnv()
+	path := strconv.Quote(t.TempDir() + "/out.txt")
+	inputs := []string{
+		`(set! out (open-output-file ` + path + `))`,
+		`(write-string "hé" out)`,
+		`(close-port out)`,
+		`(set! in (open-input-file ` + path + `))`,
+		`(read-char in)`,
+		`(read-char in)`,
+		`(eof-object? (read-char in))`,
+		`(close-port in)`}
+	for _, input := range inputs[:4] {
+		Read(input).Eval(env)
+	}
+	runEvalCases(t, env, []evalCase{
+		{inputs[4], `#\h`},
+		{inputs[5], `#\é`},
+		{inputs[6], "#t"},
+		{inputs[7], "()"}})
+	if _, err := evalString(`(read-char in)`, env); err == nil {
+		t.Errorf("expected reading a closed port to fail")
+	}
+}
+
+func TestRegex(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! digits (re-compile "[0-9]+"))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(re-match? digits "abc123")`, "#t"},
+		{`(re-match? "^[0-9]+$" "abc123")`, "#f"},
+		{`(re-find digits "a12b34")`, `"12"`},
+		{`(re-find digits "abc")`, "#f"},
+		{`(re-find-all digits "a12b34")`, `("12" "34")`},
+		{`(re-replace "([0-9]+)" "a12" "<$1>")`, `"a<12>"`}})
+	if _, err := evalString(`(re-compile "(")`, env); err == nil {
+		t.Errorf("expected a bad pattern to fail")
+	}
+}
+
+func TestErrorObjects(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! e (make-error "no such user" (quote not-found) 42))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(error? e)`, "#t"},
+		{`(error? "no such user")`, "#f"},
+		{`(error-message e)`, `"no such user"`},
+		{`(error-kind e)`, "not-found"},
+		{`(error-data e)`, "42"},
+		{`(error-kind (make-error "oops"))`, "error"},
+		{`(error-data (make-error "oops"))`, "()"}})
+}
+
+func TestFutures(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! release (chan))`).Eval(env)
+	Read(`(set! f (future (+ 1 (recv! release))))`).Eval(env)
+	if Read(`(realized? f)`).Eval(env) != False {
+		t.Errorf("expected f to wait for release")
+	}
+	runEvalCases(t, env, []evalCase{
+		{`(send! release 2)`, "2"},
+		{`(deref f)`, "3"},
+		{`(realized? f)`, "#t"}})
+	if _, err := evalString(`(deref (future (car 1)))`, env); err == nil {
+		t.Errorf("expected a failed future to fail when dereferenced")
+	}
+}
+
+// closures a future calls still see the env they were made in, which the
+// caller keeps setting. run with -race
+func TestFutureClosures(t *testing.T) {
+	env := newGlobalEnv()
+	evalString(`(set! y 1)`, env)
+	evalString(`(def f (x) (+ x y))`, env)
+	evalString(`(set! fs ())`, env)
+	for i := 0; i < 50; i++ {
+		evalString(`(set! fs (cons (future (f 1)) fs))`, env)
+		evalString(`(set! y (+ y 1))`, env)
+	}
+	if _, err := evalString(`(map deref fs)`, env); err != nil {
+		t.Errorf("expected every future to finish, got %v", err)
+	}
+}
+
+func TestChannels(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! c (chan 2))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(send! c (quote (1 2)))`, "(1 2)"},
+		{`(recv! c)`, "(1 2)"},
+		{`(select ((recv! c) v v) (default :empty))`, ":empty"},
+		{`(select ((send! c 5) :sent) (default :full))`, ":sent"},
+		{`(select ((recv! c) v (+ v 1)))`, "6"},
+		{`(close! c)`, "()"},
+		{`(select ((recv! c) v (eof-object? v)))`, "#t"}})
+	Read(`(set! out (chan))`).Eval(env)
+	Read(`(set! f (future (send! out "hi")))`).Eval(env)
+	if obj := Read(`(recv! out)`).Eval(env); obj != lispString("hi") {
+		t.Errorf("expected hi from the future, got %v", obj.Print())
+	}
+}
+
+func TestErrorsAcrossGo(t *testing.T) {
+	env := newGlobalEnv()
+	_, err := evalString(`(go-call "strconv.Atoi" "x")`, env)
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("expected the strconv error to survive the trip through lisp, got %v", err)
+	}
+	var cond *lispError
+	if !errors.As(err, &cond) || cond.kind != intern("go-error") {
+		t.Errorf("expected a go-error condition, got %v", err)
+	}
+	_, err = evalString(`(raise (make-error "bad hook" (quote hook-failed)))`, env)
+	if !errors.As(err, &cond) || cond.kind != intern("hook-failed") {
+		t.Errorf("expected the raised condition back from evalString, got %v", err)
+	}
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{`(string-length "héllo")`, "5"},
+		{`(substring "héllo" 1 3)`, `"él"`},
+		{`(substring "héllo" 3)`, `"lo"`},
+		{`(string-ref "héllo" 1)`, "#\\é"},
+		{`(string-upcase "héllo")`, `"HÉLLO"`},
+		{`(string-downcase "ÉCOLE")`, `"école"`},
+		{`(string-byte-length "héllo")`, "6"},
+		{`(string-byte-ref "é" 0)`, "195"},
+		{`(byte-substring "héllo" 0 3)`, `"hé"`}})
+	for _, input := range []string{`(string-ref "é" 1)`, `(substring "abc" 2 1)`, `(string-length 5)`} {
+		if _, err := evalString(input, env); err == nil {
+			t.Errorf("expected %v to fail", input)
+		}
+	}
+}
+
+func TestEqualAndHash(t *testing.T) {
+	env := newGlobalEnv()
+	pairs := [][2]string{
+		{`(quote (1 "two" (:three #\4)))`, `(quote (1 "two" (:three #\4)))`},
+		{`123456789012345678901234567890`, `123456789012345678901234567890`},
+		{`0.0`, `-0.0`},
+		{`(string->time "2026-10-16T09:00:00Z")`, `(string->time "2026-10-16T11:00:00+02:00")`}}
+	for _, p := range pairs {
+		a, b := Read(p[0]).Eval(env), Read(p[1]).Eval(env)
+		if !equalHelper(a, b) {
+			t.Errorf("expected %v and %v to be equal", p[0], p[1])
+		}
+		if hashValue(a) != hashValue(b) {
+			t.Errorf("expected %v and %v to hash alike", p[0], p[1])
+		}
+	}
+	if equalHelper(fixnum(1), lispString("1")) || hashValue(fixnum(1)) == hashValue(lispString("1")) {
+		t.Errorf("expected 1 and \"1\" to differ")
+	}
+	if hashValue(list(fixnum(1), fixnum(2))) == hashValue(list(fixnum(2), fixnum(1))) {
+		t.Errorf("expected list order to affect the hash")
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! h (make-hash))`).Eval(env)
+	Read(`(hash-set! h :b (quote (1 2.5 "x")))`).Eval(env)
+	Read(`(hash-set! h "a" #t)`).Eval(env)
+	Read(`(set! pairs (make-hash))`).Eval(env)
+	Read(`(hash-set! pairs 1 (quote sym))`).Eval(env)
+	Read(`(defstruct point x y)`).Eval(env)
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{`h`, `{"a":true,"b":[1,2.5,"x"]}`},
+		{`pairs`, `[[1,"sym"]]`},
+		{`(make-point 1/3 ())`, `{"x":"1/3","y":[]}`},
+		{`(quote (:k #\a))`, `["k","a"]`}}
+	for _, c := range cases {
+		got, err := marshalJSON(Read(c.input).Eval(env))
+		if err != nil || string(got) != c.want {
+			t.Errorf("expected %v -> %v, got %s (%v)", c.input, c.want, got, err)
+		}
+	}
+	if _, err := marshalJSON(Read(`(cons 1 2)`).Eval(env)); err == nil {
+		t.Errorf("expected an improper list to fail")
+	}
+}
+
+func TestQueues(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! q (queue 1 2 3))`).Eval(env)
+	Read(`(push-front! q 0)`).Eval(env)
+	Read(`(push-back! q 4)`).Eval(env)
+	Read(`(set! r (queue))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(length q)`, "5"},
+		{`(pop-front! q)`, "0"},
+		{`(pop-back! q)`, "4"},
+		{`(pop-back! q)`, "3"},
+		{`(length q)`, "2"},
+		{`(queue-empty? q)`, "#f"},
+		{`(queue-empty? r)`, "#t"}})
+	q := &deque{}
+	for i := 0; i < 10; i++ {
+		q.PushFront(fixnum(i))
+	}
+	for i := 0; i < 10; i++ {
+		if v := q.PopBack(); v != fixnum(i) {
+			t.Errorf("expected %v after wrapping, got %v", i, v.Print())
+		}
+	}
+	if _, err := evalString(`(pop-front! r)`, env); err == nil {
+		t.Errorf("expected popping an empty queue to fail")
+	}
+}
+
+func TestLexicalClosures(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(def make-adder (n) (lambda (x) (+ x n)))`).Eval(env)
+	Read(`(set! add5 (make-adder 5))`).Eval(env)
+	Read(`(def call-with-n (n f) (f 1))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(add5 10)`, "15"},
+		{`((make-adder 1) 2)`, "3"},
+		{`(call-with-n 100 add5)`, "6"},
+		{`(let ((n 7)) (add5 0))`, "5"}})
+}
+
+func TestTailCalls(t *testing.T) {
+	// far too little stack for 100000 nested calls
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+	env := newGlobalEnv()
+	Read(`(def count-down (n acc) (if (= n 0) acc (count-down (- n 1) (+ acc 1))))`).Eval(env)
+	Read(`(def even (n) (if (= n 0) #t (let ((m (- n 1))) (odd m))))`).Eval(env)
+	Read(`(def odd (n) (if (= n 0) #f (even (- n 1))))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(count-down 100000 0)`, "100000"},
+		{`(even 100001)`, "#f"}})
+}
+
+func TestConfigEnv(t *testing.T) {
+	env := configEnv()
+	for _, name := range configIntrinsics {
+		if _, ok := IntrinsicList[name]; !ok {
+			t.Errorf("config intrinsic %v is not an intrinsic", name)
+		}
+	}
+	for _, name := range []string{"print", "display", "now", "future", "generator", "chan", "slow-call-threshold!", "put", "prune-env!", "eval", "while", "do", "load"} {
+		if env.Get(name) != Nil {
+			t.Errorf("expected %v to be unbound in config files", name)
+		}
+	}
+	val, err := evalString(`(def port (base) (* base 2))
+(list :name "svc" :server (list :host "localhost" :port (port 4000)))`, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err := marshalJSON(val); err != nil || string(out) != `["name","svc","server",["host","localhost","port",8000]]` {
+		t.Errorf("expected the config to compute its value, got %s (%v)", out, err)
+	}
+}
+
+func TestRestArguments(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(def tail-of (x . rest) rest)`).Eval(env)
+	Read(`(def count-args (&rest all) (length all))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(tail-of 1 2 3)`, "(2 3)"},
+		{`(tail-of 1)`, "()"},
+		{`(count-args 1 2 3 4)`, "4"},
+		{`((lambda args args) 1 2)`, "(1 2)"},
+		{`((lambda (a &rest more) (cons a more)) 1 2)`, "(1 2)"}})
+	for _, input := range []string{`(tail-of)`, `(lambda (&rest) 1)`, `(lambda (a &rest b c) 1)`} {
+		if _, err := evalString(input, env); err == nil {
+			t.Errorf("expected %v to fail", input)
+		}
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	err := newTextGenerator(&out, dir).Run(`(def field (name type) (emit-line "	" name " " type))
+(emit-line "generating " 2 " files")
+(with-output-file "gen/point.go" (field "X" "int") (field "Y" "int"))
+(emit "done")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "generating 2 files\ndone" {
+		t.Errorf("unexpected stdout %q", out.String())
+	}
+	src, err := os.ReadFile(dir + "/gen/point.go")
+	if err != nil || string(src) != "\tX int\n\tY int\n" {
+		t.Errorf("unexpected file contents %q (%v)", src, err)
+	}
+}
+
+func TestPruneEnv(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! rate 2)`).Eval(env)
+	Read(`(def helper (x) (* x rate))`).Eval(env)
+	Read(`(def main () (helper 21))`).Eval(env)
+	Read(`(def scratch () 1)`).Eval(env)
+	Read(`(set! big (quote (1 2 3)))`).Eval(env)
+	before := measureEnv(env)
+	if got := Read(`(prune-env! (quote main))`).Eval(env); !reflect.DeepEqual(got, list(intern("big"), intern("scratch"))) {
+		t.Errorf("expected big and scratch to be pruned, got %v", got.Print())
+	}
+	if got := Read(`(main)`).Eval(env); got != fixnum(42) {
+		t.Errorf("expected main to still work, got %v", got.Print())
+	}
+	after := measureEnv(env)
+	if after.Bindings != before.Bindings-2 || after.Lambdas != 2 || after.Intrinsics != before.Intrinsics {
+		t.Errorf("unexpected stats %+v after pruning %+v", after, before)
+	}
+	runEvalCases(t, env, []evalCase{
+		{"(def square (x) (* x x))", "square"},
+		{"(def make-scaler (f) (lambda (x) (f x)))", "make-scaler"},
+		{"(set! handlers (make-hash))", "<hash 0>"},
+		{"(hash-set! handlers :square (lambda (x) (square x)))", "<lambda>"},
+		{"(set! scaled (make-scaler (lambda (x) (main))))", "<lambda>"},
+		{"(set! q (queue)) (push-back! q (delay (square 3)))", "<queue 1>"},
+		{"(prune-env! (quote handlers) (quote scaled) (quote q))", "(make-scaler)"},
+		{"((hash-get handlers :square) 4)", "16"},
+		{"(scaled 1)", "42"},
+		{"(force (pop-front! q))", "9"},
+		{"(set! c (chan 1)) (prune-env! (quote c))", "error: can't prune past <chan main.LispObject>, which may be holding lisp values"}})
+}
+
+func TestKeywordArguments(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(def connect (scheme &key host (port 80) (url (cons scheme (cons host port)))) url)`).Eval(env)
+	Read(`(def tagged (&rest all &key tag) (cons tag all))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(connect "http" :host "x")`, `("http" "x" . 80)`},
+		{`(connect "https" :port 443 :host "y")`, `("https" "y" . 443)`},
+		{`(connect "ftp")`, `("ftp" () . 80)`},
+		{`(tagged :tag 1)`, "(1 :tag 1)"}})
+	for _, input := range []string{`(connect "http" :hots "x")`, `(connect "http" :host)`, `(connect "http" "x")`, `(lambda (&key a &key b) 1)`} {
+		if _, err := evalString(input, env); err == nil {
+			t.Errorf("expected %v to fail", input)
+		}
+	}
+}
+
+func TestImplicitBegin(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! last ())`).Eval(env)
+	Read(`(def bump (x) (set! last x) (+ x 1))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(bump 41)`, "42"},
+		{`last`, "41"},
+		{`((lambda () (set! seen :yes) seen))`, ":yes"},
+		{`(let ((a 1)) (set! a 2) (+ a 1))`, "3"},
+		{`(begin 1 2 3)`, "3"},
+		{`(begin)`, "()"},
+		{`((lambda (x)) 1)`, "()"}})
+}
+
+func TestSlowCallLog(t *testing.T) {
+	var log bytes.Buffer
+	slowCallOut = &log
+	defer func() { slowCallOut = os.Stderr; slowCallThreshold.Store(0) }()
+	env := newGlobalEnv()
+	// nothing is ever sent on idle, so nap fails once its timeout passes
+	Read(`(def nap (ms) (recv! idle (timeout-context ms)))`).Eval(env)
+	Read(`(def quick (x) x)`).Eval(env)
+	Read(`(set! idle (chan))`).Eval(env)
+	Read(`(slow-call-threshold! 20)`).Eval(env)
+	evalString(`(nap 30)`, env)
+	Read(`(quick 1)`).Eval(env)
+	Read(`(slow-call-threshold! 0)`).Eval(env)
+	evalString(`(nap 30)`, env)
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "slow call: (nap 30) with (30) took ") {
+		t.Errorf("expected the nap call to be logged, got %q", log.String())
+	}
+	if strings.Contains(log.String(), "quick") || strings.Count(log.String(), "(nap 30)") != 1 {
+		t.Errorf("expected only the first nap to be logged, got %q", log.String())
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(set! t1 (string->time "2026-10-16T22:30:00Z"))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(format-number 1234567)`, `"1,234,567"`},
+		{`(format-number -1234567.891 :precision 2)`, `"-1,234,567.89"`},
+		{`(format-number 1234.5 :precision 2 :separator "." :decimal ",")`, `"1.234,50"`},
+		{`(format-number 2/3 :precision 3)`, `"0.667"`},
+		{`(format-number 999)`, `"999"`},
+		{`(format-date t1 :long)`, `"Friday, October 16, 2026"`},
+		{`(format-date t1 :long "Pacific/Auckland")`, `"Saturday, October 17, 2026"`},
+		{`(format-date t1 "Jan 2 15:04")`, `"Oct 16 22:30"`},
+		{`(time-diff (parse-date "2026-10-17 00:30:00" :datetime "Europe/Paris") t1)`, "0.0"}})
+	for _, input := range []string{`(format-number "1")`, `(format-number 1 :width 3)`, `(format-date t1 :nope)`, `(parse-date "x" :date)`} {
+		if _, err := evalString(input, env); err == nil {
+			t.Errorf("expected %v to fail", input)
+		}
+	}
+}
+
+func TestDecimals(t *testing.T) {
+	env := newGlobalEnv()
+	runEvalCases(t, env, []evalCase{
+		{`(decimal "19.99")`, "#d19.99"},
+		{`(+ (decimal "0.10") (decimal "0.20"))`, "#d0.30"},
+		{`(- #d10 #d0.01)`, "#d9.99"},
+		{`(* #d19.99 3)`, "#d59.97"},
+		{`(* #d1.5 #d1.5)`, "#d2.25"},
+		{`(/ #d1.00 3)`, "1/3"},
+		{`(decimal-round (/ #d1.00 3) 2)`, "#d0.33"},
+		{`(decimal-round #d2.345 2)`, "#d2.34"},
+		{`(decimal-round #d2.355 2)`, "#d2.36"},
+		{`(decimal-round #d-2.345 2)`, "#d-2.34"},
+		{`(decimal 2/3 2)`, "#d0.67"},
+		{`(equal? #d1.50 #d1.5)`, "#t"},
+		{`(< #d0.1 1/3)`, "#t"},
+		{`(format-number #d1234.5 :precision 2)`, `"1,234.50"`},
+		{`(decimal? #d0.005)`, "#t"}})
+	if got := Read(`#d-0.05`).Eval(env).Print(); got != "#d-0.05" {
+		t.Errorf("expected #d-0.05 to print as it reads, got %v", got)
+	}
+}
+
+func TestCase(t *testing.T) {
+	env := newGlobalEnv()
+	Read(`(def kind (tok) (case tok ((+ -) :additive) (("*" "/") :multiplicative) ((1 (2 3)) :number) (else :other)))`).Eval(env)
+	runEvalCases(t, env, []evalCase{
+		{`(kind (quote -))`, ":additive"},
+		{`(kind "/")`, ":multiplicative"},
+		{`(kind (quote (2 3)))`, ":number"},
+		{`(kind 4)`, ":other"},
+		{`(case 5 ((1) :one))`, "()"},
+		{`(case (+ 1 1) ((2) (set! hit #t) hit))`, "#t"}})
+	if _, err := evalString(`(case 1 (1 :one))`, env); err == nil {
+		t.Errorf("expected a clause without a data list to fail")
+	}
+}
+
+// a source string and its printed result. want starting with "error: " means
+// evaluating src should fail with a message containing the rest of it
+type evalCase struct {
+	src, want string
+}
+
+// evaluates each case's src as a body in env, in order, so earlier cases can
+// set up later ones
+func runEvalCases(t *testing.T, env Environment, cases []evalCase) {
+	t.Helper()
+	for _, c := range cases {
+		obj, err := evalString("(begin "+c.src+")", env)
+		if msg, ok := strings.CutPrefix(c.want, "error: "); ok {
+			if err == nil {
+				t.Errorf("expected %v to fail with %q, got %v", c.src, msg, obj.Print())
+			} else if !strings.Contains(err.Error(), msg) {
+				t.Errorf("expected %v to fail with %q, got %q", c.src, msg, err.Error())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expected %v -> %v, got error %v", c.src, c.want, err)
+		} else if obj.Print() != c.want {
+			t.Errorf("expected %v -> %v, got %v", c.src, c.want, obj.Print())
+		}
+	}
+}
+
+var intrinsicCases = []evalCase{
+	// special forms
+	{"(quote (a 1))", "(a 1)"},
+	{"(quote)", "error: "},
+	{"(if #t 1 2)", "1"},
+	{"(if () 1 2)", "2"},
+	{"(if #f 1)", "()"},
+	{"(def sq (x) (* x x)) (sq 4)", "16"},
+	{"(def 5 (x) x)", "error: "},
+	{"((lambda (x y) (+ x y)) 1 2)", "3"},
+	{"((lambda (x) x))", "error: "},
+	{"(set! x 5)", "5"},
+	{"(set! 5 x)", "error: set! needs a name"},
+	{"(let ((a 1) (b 2)) (+ a b))", "3"},
+	{"(let* ((a 1) (b (+ a 1)) (a (* b 10))) (list a b))", "(20 2)"},
+	{"(let* ((zz 5)) zz) zz", "()"},
+	{"(let () 1)", "1"},
+	{"(let ((a 1)))", "()"},
+	{"(let* ((a 1)) (set! a 2) (+ a 1))", "3"},
+	{"(let)", "error: let needs a list of bindings"},
+	{"(let 5 1)", "error: let needs a list of bindings, got 5"},
+	{"(let ((a 1) (b)) b)", "error: let binding 2 is not a (name value) pair: (b)"},
+	{"(let* ((a 1) b) b)", "error: let* binding 2 is not a (name value) pair: b"},
+	{"(let ((1 2)) 1)", "error: let binding 1 needs a name, got 1"},
+	{`(letrec ((ev? (lambda (n) (if (= n 0) #t (od? (- n 1)))))
+	           (od? (lambda (n) (if (= n 0) #f (ev? (- n 1))))))
+	   (list (ev? 10) (od? 7)))`, "(#t #t)"},
+	{"(letrec () 1)", "1"},
+	{"(let loop ((i 0) (acc ())) (if (= i 3) acc (loop (+ i 1) (cons i acc))))", "(2 1 0)"},
+	{"(let loop ((i 0)) (if (< i 100000) (loop (+ i 1)) i))", "100000"},
+	{"(let loop ((i 0)) (if (< i 3) (loop (+ i 1)) i)) loop", "()"},
+	{"(set! i 0) (set! acc ()) (while (< i 3) (set! acc (cons i acc)) (set! i (+ i 1))) acc", "(2 1 0)"},
+	{"(while #f (car 1))", "()"},
+	{"(while)", "error: while needs a test"},
+	{"(do ((i 0 (+ i 1)) (acc () (cons i acc))) ((= i 3) acc))", "(2 1 0)"},
+	{"(do ((i 0 (+ i 1)) (k 5)) ((= i 2) (list i k)) (set! k (+ k 1)))", "(2 7)"},
+	{"(do ((i 0 (+ i 1))) ((= i 100000)))", "()"},
+	{"(set! fns ()) (do ((i 0 (+ i 1))) ((= i 2)) (set! fns (cons (lambda () i) fns))) (list ((car fns)) ((car (cdr fns))))", "(1 0)"},
+	{"(do ((i)) (#t))", "error: do variable 1 is not a (name init step) list: (i)"},
+	{"(do () 5)", "error: do needs a (test result...) end clause, got 5"},
+	{"(cond ((= 1 2) 1) ((= 1 1) 2 3) (else 4))", "3"},
+	{"(cond ((assq (quote b) (quote ((b . 2))))))", "(b . 2)"},
+	{"(cond (#f 1))", "()"},
+	{"(cond 5)", "error: cond clause 1 is not a list"},
+	{"(apply + 1 '(2 3))", "6"},
+	{"(apply (lambda (a b) (list b a)) '(1 2))", "(2 1)"},
+	{"(apply + 1)", "error: apply's last argument must be a list, got 1"},
+	{"(map + '(1 2) '(10 20 30))", "(11 22)"},
+	{"(map car '((a) (b)))", "(a b)"},
+	{"(map car 5)", "error: map expects lists, got 5"},
+	{"(eval '(+ 1 2))", "3"},
+	{"(def env-of (x) (the-environment)) (eval 'x (env-of 7))", "7"},
+	{"(eval 'x 5)", "error: eval needs an environment"},
+	{"(set! p (list 1 2)) (set-car! p 5) (set-cdr! (cdr p) '(3)) p", "(5 2 3)"},
+	{`(error "no such user" 'bob 42)`, "error: no such user bob 42"},
+	{"'(a 'b)", "(a (quote b))"},
+	{"(define (twice x) (* 2 x)) (twice 4)", "8"},
+	{"(define seven 7) seven", "7"},
+	{"(define 5 1)", "error: define needs a name"},
+	{"(list (null? ()) (number? 1) true false)", "(#t #t #t #f)"},
+	{"(list (cadr '(1 2 3)) (cddr '(1 2 3)) (caadr '(1 (2))) (cadddr '(1 2 3 4)))", "(2 (3) 2 4)"},
+	{`(display "hi") (newline) (display #\a)`, "()"},
+	{`(read (open-input-file "/dev/null"))`, "<eof>"},
+	{"(set! b 2) (set! c '(3 4)) `(a ,b ,@c)", "(a 2 3 4)"},
+	{"(quasiquote (1 (unquote (+ 1 1)) (unquote-splicing (list 3))))", "(1 2 3)"},
+	{"`(1 ,@() 2)", "(1 2)"},
+	{"`(a . ,b)", "(a . 2)"},
+	{"`((nested ,b) (,@c))", "((nested 2) (3 4))"},
+	{"`x", "x"},
+	{"`(1 `(2 ,(3 ,b)))", "(1 (quasiquote (2 (unquote (3 2)))))"},
+	{"`(1 `(,b))", "(1 (quasiquote ((unquote b))))"},
+	{"`(1 ,@b)", "error: ,@ needs a list, got 2"},
+	{"`,@c", "error: ,@ needs to be inside a list"},
+	{",b", "error: unquote outside of a quasiquote"},
+	{"(unquote-splicing c)", "error: unquote outside of a quasiquote"},
+	{"(set! g (generator (yield 1) (yield (+ 1 1)))) (list (next g) (next g))", "(1 2)"},
+	{"(list (next g) (next g))", "(<eof> <eof>)"},
+	{"(generator? g)", "#t"},
+	{"(def count-from (n) (generator (let loop ((i n)) (yield i) (loop (+ i 1))))) (set! g (count-from 5)) (next g) (next g)", "6"},
+	{"(set! g (generator (yield 1) (car 1))) (next g)", "1"},
+	{"(next g)", "error: expected a pair, got 1"},
+	{"(next g)", "<eof>"},
+	{"(set! g (generator (set! seen (quote started)))) (set! seen ()) seen", "()"},
+	{"(next g) seen", "started"},
+	{"(next 5)", "error: next needs a generator, got 5"},
+	{"(set! g (generator (yield 1) (yield 2))) (next g) (close-generator! g) (next g)", "<eof>"},
+	{"(close-generator! g) (close-generator! (generator (yield 1))) (generator? g)", "#t"},
+	{"(let loop (i) i)", "error: let binding 1 is not a (name value) pair: i"},
+	{"(letrec ((a)) a)", "error: letrec binding 1 is not a (name value) pair"},
+	{"(begin 1 2 3)", "3"},
+	{"(begin)", "()"},
+	{"(case 2 ((1) (quote one)) ((2 3) (quote few)) (else (quote many)))", "few"},
+	{"(case 9 ((1) (quote one)))", "()"},
+	{"(and 1 2 3)", "#t"},
+	{"(and 1 () (car 1))", "#f"},
+	{"(and)", "#t"},
+	{"(or () 2 (car 1))", "#t"},
+	{"(or () ())", "#f"},
+	{"(or)", "#f"},
+	{"(not ())", "#t"},
+	{"(boolean 0)", "#t"},
+	{"(delay (car 1))", "<promise>"},
+	{"(force (delay (+ 1 2)))", "3"},
+	{"(set! n 0) (force (begin (set! n (+ n 1)) n)) n", "1"},
+	{"(promise? (delay 1))", "#t"},
+	{"(def nats (n) (stream-cons n (nats (+ n 1)))) (stream-take (nats 1) 3)", "(1 2 3)"},
+	{"(stream-car (stream-cdr (nats 1)))", "2"},
+	{"(stream-car ())", "error: "},
+	{"(defstruct pt x y) (pt-y (make-pt 1 2))", "2"},
+	{"(pt-x 5)", "error: "},
+
+	// numbers
+	{"(+ 1 2 3)", "6"},
+	{"(- 10 4 1)", "5"},
+	{"(* 2 3.5)", "7.0"},
+	{"(/ 1 3)", "1/3"},
+	{"(/ 1 0)", "error: "},
+	{`(+ 1 "a")`, "error: "},
+	{"(> 3 2 1)", "#t"},
+	{"(>= 1 2)", "#f"},
+	{"(< 1 2)", "#t"},
+	{"(<= 2 2)", "#t"},
+	{"(= 1 1.0)", "#t"},
+	{`(< 1 "a")`, "error: "},
+	{"(compare 2 1)", "1"},
+	{"(num? 1/2)", "#t"},
+	{"(fixnum? 10000000000000000000000)", "#f"},
+	{"(integer? 10000000000000000000000)", "#t"},
+	{"(rational? 1/2)", "#t"},
+	{"(float? 1.5)", "#t"},
+	{"(format-number 1234567.891 :precision 2)", `"1,234,567.89"`},
+	{"(format-number 9007199254740993 :precision 2)", `"9,007,199,254,740,993.00"`},
+	{"(format-number -5 :precision 1)", `"-5.0"`},
+	{"(format-number 1/8)", `"0.125"`},
+	{"(format-number 1/3)", `"0.3333333333333333"`},
+	{`(format-number "x")`, "error: "},
+	{"(decimal 1/8 3)", "#d0.125"},
+	{"(decimal-round #d2.345 2)", "#d2.34"},
+	{"(decimal? #d1.0)", "#t"},
+
+	// lists and pairs
+	{"(cons 1 2)", "(1 . 2)"},
+	{"(car (quote (1 2)))", "1"},
+	{"(cdr (quote (1 2)))", "(2)"},
+	{"(car 1)", "error: "},
+	{"(cdr ())", "error: "},
+	{"(list 1 (+ 1 1))", "(1 2)"},
+	{"(list)", "()"},
+	{"(append (list 1 2) (+ 1 2))", "(1 2 3)"},
+	{"(length (list 1 2 3))", "3"},
+	{"(list? (list 1))", "#t"},
+	{"(pair? ())", "#f"},
+	{"(nil? ())", "#t"},
+	{"(eq? (quote a) (quote a))", "#t"},
+	{"(eq? #d1.0 #d1.00)", "#t"},
+	{`(eq? (string->time "2026-10-16T09:30:00Z") (string->time "2026-10-16T11:30:00+02:00"))`, "#t"},
+	{"(equal? (list 1 (list 2)) (list 1 (list 2)))", "#t"},
+	{"(diff (list 1 2) (list 1 3))", "(((1) 2 3))"},
+	{"(prewalk (lambda (x) (if (num? x) (+ x 1) x)) (quote (1 (2))))", "(2 (3))"},
+	{"(postwalk (lambda (x) (if (num? x) (* x 2) x)) (quote (1 (2))))", "(2 (4))"},
+	{"(sexp-select (quote (a (b 1) (b 2))) (quote (b)))", "((b 1) (b 2))"},
+	{"(sexp-select (quote (a)) 5)", "error: sexp-select expects a list pattern"},
+	{"(dot-graph (quote ((a b))))", `"digraph {\n  \"a\" -> \"b\";\n}\n"`},
+
+	// alists, hashes and queues
+	{"(assoc 2 (quote ((1 . a) (2 . b))))", "(2 . b)"},
+	{"(assq (quote c) (quote ((a . 1))))", "#f"},
+	{"(acons (quote a) 1 ())", "((a . 1))"},
+	{"(alist-get (quote b) (quote ((a . 1))) 0)", "0"},
+	{"(length (alist->hash (quote ((a . 1) (b . 2)))))", "2"},
+	{"(set! h (make-hash)) (hash-set! h (quote a) 1) (hash-get h (quote a))", "1"},
+	{"(hash-get h (quote b) 0)", "0"},
+	{"(hash-keys h)", "(a)"},
+	{"(hash-del! h (quote a)) (length h)", "0"},
+	{"(hash? h)", "#t"},
+	{"(hash-get 5 1)", "error: "},
+	{"(set! q (queue)) (push-back! q 1) (push-front! q 0) (pop-back! q)", "1"},
+	{"(pop-front! q)", "0"},
+	{"(queue-empty? q)", "#t"},
+	{"(pop-front! q)", "error: "},
+
+	// symbols, strings and chars
+	{"(symbol? (quote a))", "#t"},
+	{"(symbol->string (quote abc))", `"abc"`},
+	{`(string->symbol "abc")`, "abc"},
+	{"(symbol->string 5)", "error: "},
+	{"(put (quote plist-y) :size 3)", "3"},
+	{"(get (quote plist-y) :size)", "3"},
+	{"(symbol-plist (quote plist-y))", "(:size 3)"},
+	{"(keyword? :a)", "#t"},
+	{`(string? "a")`, "#t"},
+	{`(char? #\a)`, "#t"},
+	{"(boolean? ())", "#f"},
+	{`(char->int #\a)`, "97"},
+	{"(int->char 98)", `#\b`},
+	{`(string-length "héllo")`, "5"},
+	{`(substring "héllo" 1 3)`, `"él"`},
+	{`(substring "abc" 2 9)`, "error: "},
+	{`(string-ref "héllo" 1)`, `#\é`},
+	{`(string-upcase "abc")`, `"ABC"`},
+	{`(string-downcase "ABC")`, `"abc"`},
+	{`(string-byte-length "é")`, "2"},
+	{`(string-byte-ref "é" 0)`, "195"},
+	{`(byte-substring "héllo" 0 3)`, `"hé"`},
+	{`(re-match? (re-compile "^a+$") "aaa")`, "#t"},
+	{`(re-find "[0-9]+" "ab12cd")`, `"12"`},
+	{`(re-find "[0-9]+" "abcd")`, "#f"},
+	{`(re-find-all "[0-9]" "a1b2")`, `("1" "2")`},
+	{`(re-replace "[0-9]" "a1b2" "#")`, `"a#b#"`},
+	{`(re-compile "(")`, "error: "},
+
+	// type predicates
+	{"(lambda? (lambda (x) x))", "#t"},
+	{"(intrinsic? car)", "#t"},
+
+	// code introspection
+	{"(def uses-sq (x) (sq x)) (uses (quote uses-sq))", "(sq)"},
+	{"(used-by (quote sq))", "(uses-sq)"},
+	{"(list? (prune-env!))", "#t"},
+	{"(list? (env-stats))", "#t"},
+
+	// errors
+	{`(set! e (make-error "boom" (quote oops) 1)) (error-message e)`, `"boom"`},
+	{"(error-kind e)", "oops"},
+	{"(error-data e)", "1"},
+	{"(error? e)", "#t"},
+	{"(raise e)", "error: boom"},
+
+	// concurrency
+	{"(deref (future (+ 1 2)))", "3"},
+	{"(realized? (let ((f (future 1))) (deref f) f))", "#t"},
+	{"(set! c (chan 1)) (send! c 5) (recv! c)", "5"},
+	{"(close! c) (recv! c)", "<eof>"},
+	{"(select ((recv! (chan)) v v) (default (quote none)))", "none"},
+	{"(done? (timeout-context 1000))", "#f"},
+	{"(set! ctx (timeout-context 1000)) (cancel! ctx) (done? ctx)", "#t"},
+	{"(set! ctx (timeout-context 1)) (recv! (chan) ctx)", "error: context deadline exceeded"},
+
+	// times
+	{`(time->string (string->time "2026-10-16" :date) :date)`, `"2026-10-16"`},
+	{`(time-diff (time-add (now) 90) (now)) (> 91 (time-diff (time-add (now) 90) (now)) 89)`, "#t"},
+	{`(time-parts (string->time "2026-10-16T09:30:00Z"))`,
+		"(:year 2026 :month 10 :day 16 :hour 9 :minute 30 :second 0 :weekday 5)"},
+	{`(format-date (parse-date "2026-10-16" :date) :long)`, `"Friday, October 16, 2026"`},
+	{`(parse-date "nope" :date)`, "error: "},
+
+	// ports and output
+	{`(eof-object? (read-char (open-input-file "/dev/null")))`, "#t"},
+	{`(set! p (open-output-file "/dev/null")) (write-string "x" p) (close-port p)`, "()"},
+	{"(print 1 \"a\")", "()"},
+	{"(slow-call-threshold! 0)", "0"},
+
+	// extensions
+	{`(go-call "strings.ToUpper" "abc")`, `"ABC"`},
+	{`(go-call "strconv.Atoi" "x")`, "error: "},
+	{`(load "/nonexistent.lisp")`, "error: "},
+	{"(with-progress 2 (lambda () (progress! 2) 7))", "7"},
+	{"(with-progress 2 (lambda () (progress! 1) (progress! 5)))", "2"},
+	{"(with-progress 2 (lambda () (progress! -1)))", "error: progress! can't go backwards, got -1"},
+	{"(num? (terminal-width))", "#t"},
+	{"(clear-screen)", "()"},
+	{"(move-cursor 1 1)", "()"},
+	{`(colored "ok" :green)`, "\"\x1b[32mok\x1b[0m\""},
+	{`(colored "ok" :mauve)`, "error: colored needs a color like :red, got :mauve"},
+
+	// unbound names and non-functions
+	{"(no-such-function 1)", "error: "},
+	{"(1 2)", "error: "},
+}
+
+func TestEveryIntrinsic(t *testing.T) {
+	env := newGlobalEnv()
+	var printed, other bytes.Buffer
+	printOut, termOut, progressOut, warnOut = &printed, &other, &other, &other
+	defer func() {
+		printOut, termOut, progressOut, warnOut = os.Stdout, os.Stdout, os.Stderr, os.Stderr
+	}()
+	runEvalCases(t, env, intrinsicCases)
+	if _, err := evalString(")", env); err == nil {
+		t.Errorf("expected a stray ) to be a read error")
+	}
+	if printed.String() != "hi\na1 \"a\"\n" {
+		t.Errorf("expected print, display and newline to write exactly, got %q", printed.String())
+	}
+	bar := func(filled int, done string) string {
+		return "[" + strings.Repeat("#", filled) + strings.Repeat(" ", 40-filled) + "] " + done + "\n"
+	}
+	if want := bar(40, "2/2") + bar(40, "2/2") + bar(0, "0/2") + "\x1b[2J\x1b[H\x1b[1;1H"; other.String() != want {
+		t.Errorf("expected the progress bars and terminal codes exactly, got %q", other.String())
+	}
+
+	names := []string{"go-call"}
+	for name := range IntrinsicList {
+		names = append(names, name)
+	}
+	for _, ext := range []intrinsicSet{terminalIntrinsics, fileIntrinsics, schemeIntrinsics} {
+		for name := range ext {
+			names = append(names, name)
+		}
+	}
+	srcs := []string{}
+	for _, c := range intrinsicCases {
+		srcs = append(srcs, c.src)
+	}
+	for _, name := range names {
+		if !callsName(srcs, name) {
+			t.Errorf("expected a case calling %v", name)
+		}
+	}
+}
+
+// whether any of srcs calls name, as (name ...) or (name)
+func callsName(srcs []string, name string) bool {
+	for _, src := range srcs {
+		if strings.Contains(src, "("+name+" ") || strings.Contains(src, "("+name+")") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTutorial(t *testing.T) {
+	lessons, err := loadLessons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every hint should be a right answer
+	var answers strings.Builder
+	steps := 0
+	for _, les := range lessons {
+		for _, step := range les.steps {
+			answers.WriteString(step.hint + "\n")
+			steps++
+		}
+	}
+	var out bytes.Buffer
+	if err := runTutorial(strings.NewReader(answers.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "right!"); n != steps || steps == 0 {
+		t.Errorf("expected every hint to be right, got %v", out.String())
+	}
+
+	out.Reset()
+	runTutorial(strings.NewReader("\n(+ 1 1)\n(car 1)\n:skip\n:quit\n"), &out)
+	for _, s := range []string{"that gives 2, not 6. try (+ 1 2 3)", "error: ", "one answer is (+ 1 2 3)"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected the tutorial to say %q, got %v", s, out.String())
+		}
+	}
+}
+
+func TestExercises(t *testing.T) {
+	exercises, err := loadExercises()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exercises) == 0 {
+		t.Fatal("expected some exercises")
+	}
+	solutions := map[string]string{
+		"fizzbuzz": `(def fizzbuzz (n)
+  (if (integer? (/ n 15)) (quote fizzbuzz)
+    (if (integer? (/ n 3)) (quote fizz)
+      (if (integer? (/ n 5)) (quote buzz) n))))`,
+		"reverse": `(def rev (l) (let loop ((l l) (acc ())) (if (nil? l) acc (loop (cdr l) (cons (car l) acc)))))`,
+		"sum":     `(def sum (l) (if (nil? l) 0 (+ (car l) (sum (cdr l)))))`}
+	for _, ex := range exercises {
+		if failures := ex.verify(ex.skeleton); len(failures) == 0 {
+			t.Errorf("expected the %v skeleton to fail some tests", ex.name)
+		}
+		if failures := ex.verify(solutions[ex.name]); len(failures) != 0 {
+			t.Errorf("expected the %v solution to pass, got %v", ex.name, failures)
+		}
+	}
+
+	path := t.TempDir() + "/sum.lisp"
+	os.WriteFile(path, []byte("(def sum (l) 0)"), 0o644)
+	var out bytes.Buffer
+	err = runExercise([]string{"run", "sum", path}, &out)
+	if err == nil || out.String() != "sum: short-lists: (sum (quote (5))) gave 0, expected 5\nsum: longer-lists: (sum (quote (1 2 3 4))) gave 0, expected 10\n" {
+		t.Errorf("expected a wrong solution to fail, got %v %q", err, out.String())
+	}
+	if err := runExercise([]string{"run", "nope"}, &out); err == nil {
+		t.Errorf("expected an unknown exercise to fail")
+	}
+}
+
+func TestMetacircularEvaluator(t *testing.T) {
+	src, err := os.ReadFile("examples/mceval.lisp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := newGlobalEnv()
+	if _, err := evalString(string(src), env); err != nil {
+		t.Fatal(err)
+	}
+	runEvalCases(t, env, []evalCase{
+		{"(eval '(car (cons 1 2)) the-global-environment)", "1"},
+		{"(eval '(define (fact n) (if (= n 1) 1 (* n (fact (- n 1))))) the-global-environment)", "ok"},
+		{"(eval '(fact 10) the-global-environment)", "3628800"},
+		{`(eval '(define (append x y)
+		           (if (null? x) y (cons (car x) (append (cdr x) y))))
+		        the-global-environment)
+		  (eval '(append '(a b c) '(d e f)) the-global-environment)`, "(a b c d e f)"},
+		{"(eval '(cond ((null? '(1)) 1) (else (begin (define z 5) (set! z 6) z))) the-global-environment)", "6"},
+		{"(eval '((lambda (x y) (+ x y)) 3 4) the-global-environment)", "7"},
+		{"(eval 'nope the-global-environment)", "error: Unbound variable nope"},
+		{"(eval '((lambda (x) x)) the-global-environment)", "error: Too few arguments supplied"},
+	})
+
+	var out bytes.Buffer
+	printOut = &out
+	stdinPort = newInputPort("test", strings.NewReader("(define (sq x) (* x x))\n(sq 7)\n"))
+	defer func() {
+		printOut = os.Stdout
+		stdinPort = newInputPort("stdin", os.Stdin)
+	}()
+	// the loop only stops when eval rejects the eof object
+	if _, err := evalString("(driver-loop)", env); err == nil || !strings.Contains(err.Error(), "Unknown expression type") {
+		t.Errorf("expected the driver loop to stop at the end of input, got %v", err)
+	}
+	if !strings.Contains(out.String(), ";;; M-Eval value:\nok") || !strings.Contains(out.String(), ";;; M-Eval value:\n49") {
+		t.Errorf("expected the driver loop to print its values, got %q", out.String())
+	}
+}
+
+func TestLispTestSuite(t *testing.T) {
+	var out bytes.Buffer
+	printOut = &out
+	defer func() { printOut = os.Stdout }()
+	if err := runTestSuite("tests", &out); err != nil {
+		t.Errorf("expected the lisp test suite to pass, got %v\n%v", err, out.String())
+	}
+	paths, _ := filepath.Glob("tests/*.lisp")
+	srcs := []string{}
+	for _, path := range paths {
+		src, _ := os.ReadFile(path)
+		srcs = append(srcs, string(src))
+	}
+	for name := range IntrinsicList {
+		if !callsName(srcs, name) {
+			t.Errorf("expected a test in tests/ calling %v", name)
+		}
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(dir+"/bad.lisp", []byte(`(deftest adds (assert-equal (+ 1 1) 3))
+(deftest lists (assert-equal (list 1 (list 2 3)) (quote (1 (2 4)))))
+(deftest holds (assert (< 1 2)))
+(deftest fails-to-fail (assert-error 1))`), 0o644)
+	out.Reset()
+	err := runTestSuite(dir, &out)
+	if err == nil || err.Error() != "3 of 4 tests failed" {
+		t.Errorf("expected 3 failures, got %v", err)
+	}
+	for _, s := range []string{
+		"FAIL bad.lisp: adds: (+ 1 1) gave 2, expected 3\n",
+		"FAIL bad.lisp: lists: (list 1 (list 2 3)) gave (1 (2 3)), expected (1 (2 4)): at (1 1) got 3, expected 4\n",
+		"FAIL bad.lisp: fails-to-fail: expected an error from 1",
+		"1 passed, 3 failed"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected the report to contain %q, got %v", s, out.String())
+		}
+	}
+}
+
+func TestGeneratorGoroutines(t *testing.T) {
+	env := newGlobalEnv()
+	evalString(`(def count-from (n) (generator (let loop ((i n)) (yield i) (loop (+ i 1)))))`, env)
+	before := runtime.NumGoroutine()
+	evalString(`(set! gens (map (lambda (n) (count-from n)) (list 1 2 3 4 5 6 7 8 9 10)))`, env)
+	evalString(`(map next gens)`, env)
+	if started := runtime.NumGoroutine() - before; started != 10 {
+		t.Errorf("expected each started generator to hold a goroutine, got %d more", started)
+	}
+	evalString(`(map close-generator! gens)`, env)
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if left := runtime.NumGoroutine() - before; left > 0 {
+		t.Errorf("expected close-generator! to end every body's goroutine, %d still running", left)
+	}
+}
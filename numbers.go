@@ -0,0 +1,214 @@
+package main
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type flonum float64
+
+// (eval 1.5) -> 1.5
+func (f flonum) Eval(env Environment) LispObject {
+	return f
+}
+
+// always prints a decimal point or exponent so the value reads back as a float
+func (f flonum) Print() string {
+	s := strconv.FormatFloat(float64(f), 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// integers too large for a fixnum. results are normalized back to fixnums
+// whenever they fit, so a bignum is never equal to any fixnum
+type bignum struct {
+	val *big.Int
+}
+
+func (b bignum) Eval(env Environment) LispObject {
+	return b
+}
+
+func (b bignum) Print() string {
+	return b.val.String()
+}
+
+func normalizeInt(i *big.Int) LispObject {
+	if i.IsInt64() && int64(int(i.Int64())) == i.Int64() {
+		return fixnum(i.Int64())
+	}
+	return bignum{val: i}
+}
+
+func parseBignum(s string) (LispObject, bool) {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, false
+	}
+	return normalizeInt(i), true
+}
+
+// exact fractions such as 1/3, kept in lowest terms. like bignums they're
+// normalized away when the denominator is 1
+type ratnum struct {
+	val *big.Rat
+}
+
+func (r ratnum) Eval(env Environment) LispObject {
+	return r
+}
+
+func (r ratnum) Print() string {
+	return r.val.String()
+}
+
+func normalizeRat(r *big.Rat) LispObject {
+	if r.IsInt() {
+		return normalizeInt(new(big.Int).Set(r.Num()))
+	}
+	return ratnum{val: r}
+}
+
+// only n/m tokens are rational literals; big.Rat would also accept decimals
+func parseRatnum(s string) (LispObject, bool) {
+	if !strings.Contains(s, "/") || strings.Trim(s, "0123456789+-/") != "" {
+		return nil, false
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, false
+	}
+	return normalizeRat(r), true
+}
+
+func toRat(obj LispObject) *big.Rat {
+	switch v := obj.(type) {
+	case fixnum, bignum:
+		return new(big.Rat).SetInt(toBig(v))
+	case ratnum:
+		return v.val
+	case decimal:
+		return v.Rat()
+	}
+	panic("expected an exact number, got " + obj.Print())
+}
+
+func toBig(obj LispObject) *big.Int {
+	switch v := obj.(type) {
+	case fixnum:
+		return big.NewInt(int64(v))
+	case bignum:
+		return v.val
+	}
+	panic("expected an integer, got " + obj.Print())
+}
+
+// only tokens made of digits, signs, points and exponents are float literals,
+// which keeps ParseFloat from turning symbols like inf or nan into numbers
+func parseFlonum(s string) (LispObject, bool) {
+	if strings.Trim(s, "0123456789+-.eE") != "" || !strings.ContainsAny(s, "0123456789") {
+		return nil, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, false
+	}
+	return flonum(f), true
+}
+
+func isNumber(obj LispObject) bool {
+	switch obj.(type) {
+	case fixnum, bignum, ratnum, flonum, decimal:
+		return true
+	}
+	return false
+}
+
+func checkNumber(obj LispObject) LispObject {
+	if !isNumber(obj) {
+		panic("expected a number, got " + obj.Print())
+	}
+	return obj
+}
+
+func toFlonum(obj LispObject) flonum {
+	switch v := obj.(type) {
+	case fixnum:
+		return flonum(v)
+	case bignum:
+		f, _ := new(big.Float).SetInt(v.val).Float64()
+		return flonum(f)
+	case ratnum:
+		f, _ := v.val.Float64()
+		return flonum(f)
+	case decimal:
+		f, _ := v.Rat().Float64()
+		return flonum(f)
+	case flonum:
+		return v
+	}
+	panic("expected a number, got " + obj.Print())
+}
+
+func isExact(obj LispObject) bool {
+	switch obj.(type) {
+	case fixnum, bignum, ratnum, decimal:
+		return true
+	}
+	return false
+}
+
+// exact numbers are computed as rationals and normalized back to the smallest
+// representation that holds the result; as soon as a float is involved both
+// sides are promoted
+func arith(a, b LispObject, exactOp func(z, x, y *big.Rat) *big.Rat, floatOp func(flonum, flonum) flonum) LispObject {
+	checkNumber(a)
+	checkNumber(b)
+	if isExact(a) && isExact(b) {
+		r := exactOp(new(big.Rat), toRat(a), toRat(b))
+		_, da := a.(decimal)
+		_, db := b.(decimal)
+		if da || db {
+			return decimalResult(a, b, r)
+		}
+		return normalizeRat(r)
+	}
+	return floatOp(toFlonum(a), toFlonum(b))
+}
+
+// -1, 0 or 1 depending on how a and b compare numerically
+func compareNumbers(a, b LispObject) int {
+	checkNumber(a)
+	checkNumber(b)
+	if isExact(a) && isExact(b) {
+		return toRat(a).Cmp(toRat(b))
+	}
+	f1, f2 := toFlonum(a), toFlonum(b)
+	switch {
+	case f1 < f2:
+		return -1
+	case f1 > f2:
+		return 1
+	}
+	return 0
+}
+
+func isFlonum(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(flonum)
+	return boolToLisp(ok)
+}
+
+func isInteger(rawlist []LispObject, env Environment) LispObject {
+	switch rawlist[1].Eval(env).(type) {
+	case fixnum, bignum:
+		return True
+	}
+	return False
+}
+
+func isRational(rawlist []LispObject, env Environment) LispObject {
+	return boolToLisp(isExact(rawlist[1].Eval(env)))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// an input or output port. input ports buffer so read-char can decode runes
+type port struct {
+	name   string
+	in     *bufio.Reader
+	out    io.Writer
+	closer io.Closer
+	closed bool
+}
+
+func (p *port) Eval(env Environment) LispObject {
+	return p
+}
+func (p *port) Print() string {
+	return "<port " + p.name + ">"
+}
+
+func newInputPort(name string, r io.Reader) *port {
+	p := &port{name: name, in: bufio.NewReader(r)}
+	if c, ok := r.(io.Closer); ok {
+		p.closer = c
+	}
+	return p
+}
+
+func newOutputPort(name string, w io.Writer) *port {
+	p := &port{name: name, out: w}
+	if c, ok := w.(io.Closer); ok {
+		p.closer = c
+	}
+	return p
+}
+
+// write-string uses this when no port is given
+var stdoutPort = &port{name: "stdout", out: os.Stdout}
+
+type eofObject struct{}
+
+func (e eofObject) Eval(env Environment) LispObject {
+	return e
+}
+func (e eofObject) Print() string {
+	return "<eof>"
+}
+
+var EOF = eofObject{}
+
+func checkPort(obj LispObject) *port {
+	p, ok := obj.(*port)
+	if !ok {
+		panic("expected a port, got " + obj.Print())
+	}
+	if p.closed {
+		panic("port " + p.name + " is closed")
+	}
+	return p
+}
+
+// (open-input-file "notes.txt") -> <port notes.txt>
+func openInputFile(rawlist []LispObject, env Environment) LispObject {
+	name := string(rawlist[1].Eval(env).(lispString))
+	f, err := os.Open(name)
+	if err != nil {
+		panic(err.Error())
+	}
+	return newInputPort(name, f)
+}
+
+// (open-output-file "notes.txt") -> <port notes.txt>, truncating the file
+func openOutputFile(rawlist []LispObject, env Environment) LispObject {
+	name := string(rawlist[1].Eval(env).(lispString))
+	f, err := os.Create(name)
+	if err != nil {
+		panic(err.Error())
+	}
+	return newOutputPort(name, f)
+}
+
+// closing a port twice is harmless
+func closePort(rawlist []LispObject, env Environment) LispObject {
+	p, ok := rawlist[1].Eval(env).(*port)
+	if !ok {
+		panic("close-port needs a port")
+	}
+	if !p.closed && p.closer != nil {
+		if err := p.closer.Close(); err != nil {
+			panic(err.Error())
+		}
+	}
+	p.closed = true
+	return Nil
+}
+
+// (read-char port) -> #\a, or the eof object once the port is exhausted
+func readChar(rawlist []LispObject, env Environment) LispObject {
+	p := checkPort(rawlist[1].Eval(env))
+	if p.in == nil {
+		panic("port " + p.name + " is not an input port")
+	}
+	r, _, err := p.in.ReadRune()
+	if err == io.EOF {
+		return EOF
+	}
+	if err != nil {
+		panic(err.Error())
+	}
+	return lispChar(r)
+}
+
+// (write-string "hi" port) -> "hi", writing to stdout without a port
+func writeString(rawlist []LispObject, env Environment) LispObject {
+	s := rawlist[1].Eval(env).(lispString)
+	p := stdoutPort
+	if len(rawlist) > 2 {
+		p = checkPort(rawlist[2].Eval(env))
+	}
+	if p.out == nil {
+		panic("port " + p.name + " is not an output port")
+	}
+	if _, err := io.WriteString(p.out, string(s)); err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+// (eof-object? (read-char port)) -> #t at the end of input
+func isEOFObject(rawlist []LispObject, env Environment) LispObject {
+	_, ok := rawlist[1].Eval(env).(eofObject)
+	return boolToLisp(ok)
+}
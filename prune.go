@@ -0,0 +1,195 @@
+package main
+
+// every symbol mentioned anywhere in obj, so variables count as well as calls.
+// it looks inside containers, and the envs closures and promises were made
+// in, since those can hold lambdas that mention more names. seen stops it
+// going round cycles. values it can't see into, like a channel that may hold
+// anything, make it panic rather than let something live be pruned
+func mentionedNames(obj LispObject, found map[string]bool, seen map[interface{}]bool) {
+	switch v := obj.(type) {
+	case *symbol:
+		found[v.name] = true
+	case *cons:
+		if !seen[v] {
+			seen[v] = true
+			mentionedNames(v.car, found, seen)
+			mentionedNames(v.cdr, found, seen)
+		}
+	case lambda:
+		mentionedNames(v.fn, found, seen)
+		envNames(v.env, found, seen)
+	case *promise:
+		if !seen[v] {
+			seen[v] = true
+			if v.expr != nil {
+				mentionedNames(v.expr, found, seen)
+			}
+			if v.value != nil {
+				mentionedNames(v.value, found, seen)
+			}
+			envNames(&v.env, found, seen)
+		}
+	case *hashTable:
+		if !seen[v] {
+			seen[v] = true
+			for _, bucket := range v.buckets {
+				for _, entry := range bucket {
+					mentionedNames(entry.key, found, seen)
+					mentionedNames(entry.val, found, seen)
+				}
+			}
+		}
+	case *record:
+		if !seen[v] {
+			seen[v] = true
+			for _, field := range v.fields {
+				mentionedNames(field, found, seen)
+			}
+		}
+	case *deque:
+		if !seen[v] {
+			seen[v] = true
+			for _, item := range v.items {
+				if item != nil {
+					mentionedNames(item, found, seen)
+				}
+			}
+		}
+	case *lispError:
+		mentionedNames(v.data, found, seen)
+	case *generator:
+		if !seen[v.genState] {
+			seen[v.genState] = true
+			mentionedNames(v.body, found, seen)
+			envNames(&v.env, found, seen)
+		}
+	case lispEnv:
+		envNames(&v.env, found, seen)
+	case *future:
+		select {
+		case <-v.done:
+			if v.value != nil {
+				mentionedNames(v.value, found, seen)
+			}
+		default:
+			panic("can't prune while a future is still running")
+		}
+	case *lispChan:
+		panic("can't prune past " + v.Print() + ", which may be holding lisp values")
+	}
+}
+
+// the names mentioned by the values bound in env and its parents, short of the
+// root env, whose bindings are what's being pruned
+func envNames(env *Environment, found map[string]bool, seen map[interface{}]bool) {
+	for e := env; e != nil && e.Parent != nil; e = e.Parent {
+		if seen[e] {
+			return
+		}
+		seen[e] = true
+		for _, val := range e.Fields {
+			mentionedNames(val.(LispObject), found, seen)
+		}
+	}
+}
+
+// the outermost env, where long sessions accumulate definitions
+func rootEnv(env Environment) *Environment {
+	e := &env
+	for e.Parent != nil {
+		e = e.Parent
+	}
+	return e
+}
+
+// deletes the bindings in env's root that can't be reached from the keep
+// names, intrinsics or constants like true, following the names each kept
+// value mentions, and returns what it removed in sorted order. deleting drops
+// the map's references, so the garbage values can be collected
+func pruneBindings(env Environment, keep []string) []string {
+	root := rootEnv(env)
+	envLock.Lock()
+	defer envLock.Unlock()
+	live := map[string]bool{}
+	seen := map[interface{}]bool{}
+	pending := append([]string{}, keep...)
+	for name, val := range root.Fields {
+		switch val.(type) {
+		case Intrinsic, lispBool:
+			pending = append(pending, name)
+		}
+	}
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if live[name] {
+			continue
+		}
+		live[name] = true
+		if val, ok := root.Fields[name]; ok {
+			found := map[string]bool{}
+			mentionedNames(val.(LispObject), found, seen)
+			for ref := range found {
+				pending = append(pending, ref)
+			}
+		}
+	}
+	removed := map[string]bool{}
+	for name := range root.Fields {
+		if !live[name] {
+			delete(root.Fields, name)
+			removed[name] = true
+		}
+	}
+	return sortedNames(removed)
+}
+
+// (prune-env! (quote main) (quote handler)) -> the names it removed
+func pruneEnv(rawlist []LispObject, env Environment) LispObject {
+	keep := []string{}
+	for _, arg := range rawlist[1:] {
+		sym, ok := arg.Eval(env).(*symbol)
+		if !ok {
+			panic("prune-env! keeps names given as symbols")
+		}
+		keep = append(keep, sym.name)
+	}
+	return namesToList(pruneBindings(env, keep))
+}
+
+// sizes of an environment chain, for watching long running sessions
+type envSizes struct {
+	Depth      int
+	Bindings   int
+	Lambdas    int
+	Intrinsics int
+}
+
+func measureEnv(env Environment) envSizes {
+	stats := envSizes{}
+	envLock.RLock()
+	defer envLock.RUnlock()
+	for e := &env; e != nil; e = e.Parent {
+		stats.Depth++
+		stats.Bindings += len(e.Fields)
+		for _, val := range e.Fields {
+			switch val.(type) {
+			case lambda:
+				stats.Lambdas++
+			case Intrinsic:
+				stats.Intrinsics++
+			}
+		}
+	}
+	return stats
+}
+
+// (env-stats) -> (:depth 1 :bindings 210 :lambdas 3 :intrinsics 200)
+func envStats(rawlist []LispObject, env Environment) LispObject {
+	s := measureEnv(env)
+	return list(
+		keyword("depth"), fixnum(s.Depth),
+		keyword("bindings"), fixnum(s.Bindings),
+		keyword("lambdas"), fixnum(s.Lambdas),
+		keyword("intrinsics"), fixnum(s.Intrinsics))
+}
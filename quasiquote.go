@@ -0,0 +1,68 @@
+package main
+
+// the argument of a (tag x) form, when form is one
+func taggedArg(form LispObject, tag string) (LispObject, bool) {
+	c, ok := form.(*cons)
+	if !ok || c.car != intern(tag) {
+		return nil, false
+	}
+	rest, ok := c.cdr.(*cons)
+	if !ok || rest.cdr != Nil {
+		panic(tag + " takes exactly one form, got " + form.Print())
+	}
+	return rest.car, true
+}
+
+// `(a ,b ,@c) -> (a 2 3 4) with b bound to 2 and c to (3 4). the template is
+// copied as data except for unquoted forms, which are evaluated, and spliced
+// in when unquoted with ,@. a nested quasiquote keeps its unquotes as they are
+// until they're deeper than the quasiquotes around them
+func quasiquote(rawlist []LispObject, env Environment) LispObject {
+	return quasiquoteForm(rawlist[1], 1, env)
+}
+
+func quasiquoteForm(form LispObject, depth int, env Environment) LispObject {
+	if arg, ok := taggedArg(form, "unquote"); ok {
+		if depth == 1 {
+			return arg.Eval(env)
+		}
+		return list(intern("unquote"), quasiquoteForm(arg, depth-1, env))
+	}
+	if arg, ok := taggedArg(form, "unquote-splicing"); ok {
+		if depth == 1 {
+			panic(",@ needs to be inside a list, got " + form.Print())
+		}
+		return list(intern("unquote-splicing"), quasiquoteForm(arg, depth-1, env))
+	}
+	if arg, ok := taggedArg(form, "quasiquote"); ok {
+		return list(intern("quasiquote"), quasiquoteForm(arg, depth+1, env))
+	}
+	items := []LispObject{}
+	obj := form
+	for {
+		c, ok := obj.(*cons)
+		if !ok {
+			return listWithTail(items, obj)
+		}
+		if obj != form && (c.car == intern("unquote") || c.car == intern("quasiquote")) {
+			// `(a . ,b) reads as (a unquote b)
+			return listWithTail(items, quasiquoteForm(c, depth, env))
+		}
+		if arg, ok := taggedArg(c.car, "unquote-splicing"); ok && depth == 1 {
+			val := arg.Eval(env)
+			spliced, ok := listToSlice(val)
+			if !ok {
+				panic(",@ needs a list, got " + val.Print())
+			}
+			items = append(items, spliced...)
+		} else {
+			items = append(items, quasiquoteForm(c.car, depth, env))
+		}
+		obj = c.cdr
+	}
+}
+
+// (unquote x) and (unquote-splicing x) only mean something in a quasiquote
+func unquoteOutside(rawlist []LispObject, env Environment) LispObject {
+	panic("unquote outside of a quasiquote")
+}
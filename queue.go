@@ -0,0 +1,108 @@
+package main
+
+import "strconv"
+
+// a mutable double ended queue kept in a ring buffer, so pushes and pops at
+// either end don't copy or alias other queues' storage
+type deque struct {
+	items []LispObject
+	head  int
+	size  int
+}
+
+func (q *deque) Eval(env Environment) LispObject {
+	return q
+}
+func (q *deque) Print() string {
+	return "<queue " + strconv.Itoa(q.size) + ">"
+}
+
+func (q *deque) grow() {
+	if q.size < len(q.items) {
+		return
+	}
+	items := make([]LispObject, max(4, 2*len(q.items)))
+	for i := 0; i < q.size; i++ {
+		items[i] = q.items[(q.head+i)%len(q.items)]
+	}
+	q.items = items
+	q.head = 0
+}
+
+func (q *deque) PushFront(v LispObject) {
+	q.grow()
+	q.head = (q.head - 1 + len(q.items)) % len(q.items)
+	q.items[q.head] = v
+	q.size++
+}
+
+func (q *deque) PushBack(v LispObject) {
+	q.grow()
+	q.items[(q.head+q.size)%len(q.items)] = v
+	q.size++
+}
+
+func (q *deque) PopFront() LispObject {
+	if q.size == 0 {
+		panic("pop from an empty queue")
+	}
+	v := q.items[q.head]
+	q.items[q.head] = nil
+	q.head = (q.head + 1) % len(q.items)
+	q.size--
+	return v
+}
+
+func (q *deque) PopBack() LispObject {
+	if q.size == 0 {
+		panic("pop from an empty queue")
+	}
+	i := (q.head + q.size - 1) % len(q.items)
+	v := q.items[i]
+	q.items[i] = nil
+	q.size--
+	return v
+}
+
+func checkQueue(obj LispObject) *deque {
+	q, ok := obj.(*deque)
+	if !ok {
+		panic("expected a queue, got " + obj.Print())
+	}
+	return q
+}
+
+// (queue 1 2 3) -> <queue 3> with 1 at the front
+func makeQueue(rawlist []LispObject, env Environment) LispObject {
+	q := &deque{}
+	for _, arg := range rawlist[1:] {
+		q.PushBack(arg.Eval(env))
+	}
+	return q
+}
+
+// (push-front! q 0) -> q
+func pushFront(rawlist []LispObject, env Environment) LispObject {
+	q := checkQueue(rawlist[1].Eval(env))
+	q.PushFront(rawlist[2].Eval(env))
+	return q
+}
+
+func pushBack(rawlist []LispObject, env Environment) LispObject {
+	q := checkQueue(rawlist[1].Eval(env))
+	q.PushBack(rawlist[2].Eval(env))
+	return q
+}
+
+// (pop-front! q) -> the removed element, failing on an empty queue
+func popFront(rawlist []LispObject, env Environment) LispObject {
+	return checkQueue(rawlist[1].Eval(env)).PopFront()
+}
+
+func popBack(rawlist []LispObject, env Environment) LispObject {
+	return checkQueue(rawlist[1].Eval(env)).PopBack()
+}
+
+func isQueueEmpty(rawlist []LispObject, env Environment) LispObject {
+	return boolToLisp(checkQueue(rawlist[1].Eval(env)).size == 0)
+}
@@ -0,0 +1,76 @@
+package main
+
+import "regexp"
+
+type lispRegex struct {
+	re *regexp.Regexp
+}
+
+func (r *lispRegex) Eval(env Environment) LispObject {
+	return r
+}
+func (r *lispRegex) Print() string {
+	return "<regex " + r.re.String() + ">"
+}
+
+func compileRegex(pattern string) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(err.Error())
+	}
+	return re
+}
+
+// the re-* functions take either a compiled regex or a pattern string
+func checkRegex(obj LispObject) *regexp.Regexp {
+	switch r := obj.(type) {
+	case *lispRegex:
+		return r.re
+	case lispString:
+		return compileRegex(string(r))
+	}
+	panic("expected a regex, got " + obj.Print())
+}
+
+// (re-compile "[0-9]+") -> <regex [0-9]+>
+func reCompile(rawlist []LispObject, env Environment) LispObject {
+	pattern := rawlist[1].Eval(env).(lispString)
+	return &lispRegex{re: compileRegex(string(pattern))}
+}
+
+// (re-match? "[0-9]+" "abc123") -> #t
+func reMatch(rawlist []LispObject, env Environment) LispObject {
+	re := checkRegex(rawlist[1].Eval(env))
+	s := rawlist[2].Eval(env).(lispString)
+	return boolToLisp(re.MatchString(string(s)))
+}
+
+// (re-find "[0-9]+" "a12b34") -> "12", or #f without a match
+func reFind(rawlist []LispObject, env Environment) LispObject {
+	re := checkRegex(rawlist[1].Eval(env))
+	s := rawlist[2].Eval(env).(lispString)
+	loc := re.FindStringIndex(string(s))
+	if loc == nil {
+		return False
+	}
+	return s[loc[0]:loc[1]]
+}
+
+// (re-find-all "[0-9]+" "a12b34") -> ("12" "34")
+func reFindAll(rawlist []LispObject, env Environment) LispObject {
+	re := checkRegex(rawlist[1].Eval(env))
+	s := rawlist[2].Eval(env).(lispString)
+	matches := []LispObject{}
+	for _, m := range re.FindAllString(string(s), -1) {
+		matches = append(matches, lispString(m))
+	}
+	return list(matches...)
+}
+
+// (re-replace "([0-9]+)" "a12" "<$1>") -> "a<12>"
+func reReplace(rawlist []LispObject, env Environment) LispObject {
+	re := checkRegex(rawlist[1].Eval(env))
+	s := rawlist[2].Eval(env).(lispString)
+	repl := rawlist[3].Eval(env).(lispString)
+	return lispString(re.ReplaceAllString(string(s), string(repl)))
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// the names older scheme programs, like the SICP metacircular evaluator in
+// examples/, expect where this lisp spells things differently
+var schemeIntrinsics = intrinsicSet{
+	"define":  Intrinsic{op: define},
+	"null?":   Intrinsic{op: isNil},
+	"number?": Intrinsic{op: isNum},
+	"display": Intrinsic{op: display},
+	"newline": Intrinsic{op: newline},
+	"read":    Intrinsic{op: readForm}}
+
+// binds schemeIntrinsics, the cadr family of car and cdr compositions, and
+// true and false
+type schemeCompat struct{}
+
+func (schemeCompat) Register(env Environment) error {
+	schemeIntrinsics.Register(env)
+	paths := []string{"a", "d"}
+	for depth := 2; depth <= 4; depth++ {
+		longer := []string{}
+		for _, path := range paths {
+			longer = append(longer, "a"+path, "d"+path)
+		}
+		paths = longer
+		for _, path := range paths {
+			env.Put("c"+path+"r", cxr(path))
+		}
+	}
+	env.Put("true", True)
+	env.Put("false", False)
+	return nil
+}
+
+// (cadr x) is (car (cdr x)): the letters apply from right to left
+func cxr(path string) Intrinsic {
+	return Intrinsic{op: func(rawlist []LispObject, env Environment) LispObject {
+		obj := rawlist[1].Eval(env)
+		for i := len(path) - 1; i >= 0; i-- {
+			c := checkCons(obj)
+			if path[i] == 'a' {
+				obj = c.car
+			} else {
+				obj = c.cdr
+			}
+		}
+		return obj
+	}}
+}
+
+// (define (f x) body...) defines a function like def does, and (define x 5)
+// a variable, in the current environment
+func define(rawlist []LispObject, env Environment) LispObject {
+	switch target := rawlist[1].(type) {
+	case *cons:
+		name, ok := target.car.(*symbol)
+		if !ok {
+			panic("define needs a function name, got " + target.car.Print())
+		}
+		env.Put(name.name, mklambda(append([]LispObject{name, target.cdr}, rawlist[2:]...), env))
+		return name
+	case *symbol:
+		var val LispObject = Nil
+		if len(rawlist) > 2 {
+			val = rawlist[2].Eval(env)
+		}
+		env.Put(target.name, val)
+		return target
+	}
+	panic("define needs a name or (name args...), got " + rawlist[1].Print())
+}
+
+// the port display and newline write to when they aren't given one
+func optionalOutPort(rawlist []LispObject, i int, env Environment) *port {
+	if len(rawlist) > i {
+		return checkPort(rawlist[i].Eval(env))
+	}
+	return &port{name: "stdout", out: printOut}
+}
+
+// (display "hi") writes hi, strings and chars without their quotes
+func display(rawlist []LispObject, env Environment) LispObject {
+	out := optionalOutPort(rawlist, 2, env).out
+	switch v := rawlist[1].Eval(env).(type) {
+	case lispString:
+		fmt.Fprint(out, string(v))
+	case lispChar:
+		fmt.Fprint(out, string(rune(v)))
+	default:
+		fmt.Fprint(out, v.Print())
+	}
+	return Nil
+}
+
+func newline(rawlist []LispObject, env Environment) LispObject {
+	fmt.Fprintln(optionalOutPort(rawlist, 1, env).out)
+	return Nil
+}
+
+// read takes its forms from here when it isn't given a port
+var stdinPort = newInputPort("stdin", os.Stdin)
+
+// (read) -> the next form typed on stdin, or the eof object once it ends.
+// the rest of the line after the form is dropped
+func readForm(rawlist []LispObject, env Environment) LispObject {
+	p := stdinPort
+	if len(rawlist) > 1 {
+		p = checkPort(rawlist[1].Eval(env))
+	}
+	var src strings.Builder
+	for {
+		line, err := p.in.ReadString('\n')
+		src.WriteString(line)
+		if !incomplete(src.String()) {
+			if forms := ReadAll(src.String()); len(forms) > 0 {
+				return forms[0]
+			}
+		}
+		if err != nil {
+			return EOF
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// applications taking longer than this are logged to slowCallOut. zero, the
+// default, turns logging off
+var slowCallThreshold atomic.Int64
+
+var slowCallOut io.Writer = os.Stderr
+
+// printed forms longer than this are cut short in the log
+const slowCallWidth = 80
+
+// one application being timed. args are the evaluated arguments of a lambda
+// call, or nil for intrinsics, whose arguments are shown as written
+type slowCall struct {
+	form  *cons
+	args  []LispObject
+	start time.Time
+}
+
+// starts timing form, or returns nil when logging is off
+func startSlowCall(form *cons) *slowCall {
+	if slowCallThreshold.Load() <= 0 {
+		return nil
+	}
+	return &slowCall{form: form, start: time.Now()}
+}
+
+func truncate(s string) string {
+	if len(s) <= slowCallWidth {
+		return s
+	}
+	return s[:slowCallWidth-3] + "..."
+}
+
+func (s *slowCall) finish() {
+	elapsed := time.Since(s.start)
+	threshold := time.Duration(slowCallThreshold.Load())
+	if threshold <= 0 || elapsed < threshold {
+		return
+	}
+	line := "slow call: " + truncate(s.form.Print())
+	if s.args != nil {
+		line += " with " + truncate(list(s.args...).Print())
+	}
+	fmt.Fprintf(slowCallOut, "%v took %v\n", line, elapsed)
+}
+
+// (slow-call-threshold! 250) logs every application taking 250ms or more,
+// (slow-call-threshold! 0) stops
+func setSlowCallThreshold(rawlist []LispObject, env Environment) LispObject {
+	ms := rawlist[1].Eval(env)
+	slowCallThreshold.Store(int64(float64(toFlonum(ms)) * float64(time.Millisecond)))
+	return ms
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func checkString(obj LispObject) string {
+	s, ok := obj.(lispString)
+	if !ok {
+		panic("expected a string, got " + obj.Print())
+	}
+	return string(s)
+}
+
+func checkIndex(obj LispObject, limit int) int {
+	i, ok := obj.(fixnum)
+	if !ok || i < 0 || int(i) > limit {
+		panic(fmt.Sprintf("index %v out of range 0..%d", obj.Print(), limit))
+	}
+	return int(i)
+}
+
+// start and optional end arguments at rawlist[2] and rawlist[3], within n
+func sliceBounds(rawlist []LispObject, n int, env Environment) (int, int) {
+	start := checkIndex(rawlist[2].Eval(env), n)
+	end := n
+	if len(rawlist) > 3 {
+		end = checkIndex(rawlist[3].Eval(env), n)
+	}
+	if end < start {
+		panic(fmt.Sprintf("substring end %d is before start %d", end, start))
+	}
+	return start, end
+}
+
+// (string-length "héllo") -> 5
+func stringLength(rawlist []LispObject, env Environment) LispObject {
+	return fixnum(len([]rune(checkString(rawlist[1].Eval(env)))))
+}
+
+// (substring "héllo" 1 3) -> "él", running to the end without an end index
+func substring(rawlist []LispObject, env Environment) LispObject {
+	runes := []rune(checkString(rawlist[1].Eval(env)))
+	start, end := sliceBounds(rawlist, len(runes), env)
+	return lispString(runes[start:end])
+}
+
+// (string-ref "héllo" 1) -> #\é
+func stringRef(rawlist []LispObject, env Environment) LispObject {
+	runes := []rune(checkString(rawlist[1].Eval(env)))
+	return lispChar(runes[checkIndex(rawlist[2].Eval(env), len(runes)-1)])
+}
+
+// (string-upcase "héllo") -> "HÉLLO"
+func stringUpcase(rawlist []LispObject, env Environment) LispObject {
+	return lispString(strings.ToUpper(checkString(rawlist[1].Eval(env))))
+}
+
+func stringDowncase(rawlist []LispObject, env Environment) LispObject {
+	return lispString(strings.ToLower(checkString(rawlist[1].Eval(env))))
+}
+
+// (string-byte-length "héllo") -> 6
+func stringByteLength(rawlist []LispObject, env Environment) LispObject {
+	return fixnum(len(checkString(rawlist[1].Eval(env))))
+}
+
+// (string-byte-ref "é" 0) -> 195
+func stringByteRef(rawlist []LispObject, env Environment) LispObject {
+	s := checkString(rawlist[1].Eval(env))
+	return fixnum(s[checkIndex(rawlist[2].Eval(env), len(s)-1)])
+}
+
+// (byte-substring "héllo" 0 3) -> "hé". the result may split a rune
+func byteSubstring(rawlist []LispObject, env Environment) LispObject {
+	s := checkString(rawlist[1].Eval(env))
+	start, end := sliceBounds(rawlist, len(s), env)
+	return lispString(s[start:end])
+}
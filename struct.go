@@ -0,0 +1,70 @@
+package main
+
+import "strconv"
+
+type recordType struct {
+	name   string
+	fields []string
+}
+
+// an instance of a type declared with defstruct
+type record struct {
+	typ    *recordType
+	fields []LispObject
+}
+
+func (r *record) Eval(env Environment) LispObject {
+	return r
+}
+func (r *record) Print() string {
+	buf := "<" + r.typ.name
+	for _, val := range r.fields {
+		buf += " " + val.Print()
+	}
+	return buf + ">"
+}
+
+func (t *recordType) check(obj LispObject) *record {
+	r, ok := obj.(*record)
+	if !ok || r.typ != t {
+		panic("expected a " + t.name + ", got " + obj.Print())
+	}
+	return r
+}
+
+// (defstruct point x y) defines make-point, point?, point-x, point-y,
+// set-point-x! and set-point-y!, returning point
+func defstruct(rawlist []LispObject, env Environment) LispObject {
+	name := rawlist[1].(*symbol)
+	typ := &recordType{name: name.name}
+	for _, field := range rawlist[2:] {
+		typ.fields = append(typ.fields, field.(*symbol).name)
+	}
+
+	env.Put("make-"+typ.name, Intrinsic{op: func(rawlist []LispObject, env Environment) LispObject {
+		if len(rawlist)-1 != len(typ.fields) {
+			panic("make-" + typ.name + " expects " + strconv.Itoa(len(typ.fields)) + " arguments")
+		}
+		r := &record{typ: typ}
+		for _, arg := range rawlist[1:] {
+			r.fields = append(r.fields, arg.Eval(env))
+		}
+		return r
+	}})
+	env.Put(typ.name+"?", Intrinsic{op: func(rawlist []LispObject, env Environment) LispObject {
+		r, ok := rawlist[1].Eval(env).(*record)
+		return boolToLisp(ok && r.typ == typ)
+	}})
+	for i, field := range typ.fields {
+		i := i
+		env.Put(typ.name+"-"+field, Intrinsic{op: func(rawlist []LispObject, env Environment) LispObject {
+			return typ.check(rawlist[1].Eval(env)).fields[i]
+		}})
+		env.Put("set-"+typ.name+"-"+field+"!", Intrinsic{op: func(rawlist []LispObject, env Environment) LispObject {
+			r := typ.check(rawlist[1].Eval(env))
+			r.fields[i] = rawlist[2].Eval(env)
+			return r.fields[i]
+		}})
+	}
+	return name
+}
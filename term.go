@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// progress bars are drawn to stderr so they don't mix with printed results
+var progressOut io.Writer = os.Stderr
+
+type progressBar struct {
+	total fixnum
+	done  fixnum
+}
+
+// the bar for the innermost with-progress, nil outside of one
+var currentProgress *progressBar
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// redraws the bar in place. when not writing to a terminal only the final
+// state is printed, so logs don't fill up with carriage returns
+func (p *progressBar) draw(final bool) {
+	tty := isTerminal(progressOut)
+	if !tty && !final {
+		return
+	}
+	width := 40
+	filled := width
+	if p.total > 0 && p.done < p.total {
+		filled = int(p.done) * width / int(p.total)
+	}
+	bar := strings.Repeat("#", filled) + strings.Repeat(" ", width-filled)
+	if tty {
+		fmt.Fprint(progressOut, "\r")
+	}
+	fmt.Fprintf(progressOut, "[%s] %d/%d", bar, p.done, p.total)
+	if final {
+		fmt.Fprintln(progressOut)
+	}
+}
+
+// (with-progress 10 (lambda () ...)) calls the thunk with a fresh progress bar
+func withProgress(rawlist []LispObject, env Environment) LispObject {
+	total := rawlist[1].Eval(env).(fixnum)
+	outer := currentProgress
+	currentProgress = &progressBar{total: total}
+	defer func() {
+		currentProgress.draw(true)
+		currentProgress = outer
+	}()
+	currentProgress.draw(false)
+	return apply(rawlist[2].Eval(env), []LispObject{}, env)
+}
+
+// (progress! 1) advances the current bar, returning how much has been done.
+// it stops at the bar's total and can't go backwards
+func progress(rawlist []LispObject, env Environment) LispObject {
+	if currentProgress == nil {
+		panic("progress! called outside of with-progress")
+	}
+	step := fixnum(1)
+	if len(rawlist) > 1 {
+		step = rawlist[1].Eval(env).(fixnum)
+	}
+	if step < 0 {
+		panic("progress! can't go backwards, got " + step.Print())
+	}
+	currentProgress.done += step
+	if currentProgress.done > currentProgress.total {
+		currentProgress.done = currentProgress.total
+	}
+	currentProgress.draw(false)
+	return currentProgress.done
+}
+
+// terminal output goes to stdout alongside everything print writes
+var termOut io.Writer = os.Stdout
+
+// (terminal-width) -> 80
+func terminalWidth(rawlist []LispObject, env Environment) LispObject {
+	if width := ttyWidth(os.Stdout.Fd()); width > 0 {
+		return fixnum(width)
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return fixnum(width)
+	}
+	return fixnum(80)
+}
+
+func clearScreen(rawlist []LispObject, env Environment) LispObject {
+	fmt.Fprint(termOut, "\x1b[2J\x1b[H")
+	return Nil
+}
+
+// (move-cursor row col), both counted from 1 like the terminal does
+func moveCursor(rawlist []LispObject, env Environment) LispObject {
+	row := rawlist[1].Eval(env).(fixnum)
+	col := rawlist[2].Eval(env).(fixnum)
+	fmt.Fprintf(termOut, "\x1b[%d;%dH", row, col)
+	return Nil
+}
+
+// ansi foreground codes for colored
+var terminalColors = map[keyword]int{
+	"black": 30, "red": 31, "green": 32, "yellow": 33,
+	"blue": 34, "magenta": 35, "cyan": 36, "white": 37}
+
+// (colored "error" :red) -> the string wrapped in the color's escape codes
+func colored(rawlist []LispObject, env Environment) LispObject {
+	text, ok := rawlist[1].Eval(env).(lispString)
+	if !ok {
+		panic("colored needs a string, got " + rawlist[1].Print())
+	}
+	color, ok := rawlist[2].Eval(env).(keyword)
+	code, known := terminalColors[color]
+	if !ok || !known {
+		panic("colored needs a color like :red, got " + rawlist[2].Print())
+	}
+	return lispString(fmt.Sprintf("\x1b[%dm%s\x1b[0m", code, text))
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// asks the kernel for the size of the terminal on fd, 0 if it isn't one
+func ttyWidth(fd uintptr) int {
+	var size struct {
+		rows, cols, xpixel, ypixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd,
+		uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0
+	}
+	return int(size.cols)
+}
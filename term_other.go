@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// terminal size isn't queried outside of linux; callers fall back to $COLUMNS
+func ttyWidth(fd uintptr) int {
+	return 0
+}
@@ -0,0 +1,51 @@
+;;; hashes, queues, records and channels
+
+(deftest hashes
+  (define h (make-hash))
+  (hash-set! h 'a 1)
+  (assert-equal (hash-get h 'a) 1)
+  (assert-equal (hash-get h 'b 0) 0)
+  (hash-del! h 'a)
+  (assert-equal (length h) 0))
+
+(deftest queues
+  (define q (queue))
+  (push-back! q 1)
+  (push-front! q 0)
+  (assert-equal (pop-front! q) 0)
+  (assert-equal (pop-back! q) 1)
+  (assert (queue-empty? q)))
+
+(deftest records
+  (defstruct pt x y)
+  (define p (make-pt 1 2))
+  (set-pt-x! p 5)
+  (assert-equal (pt-x p) 5)
+  (assert (equal? (make-pt 1 2) (make-pt 1 2))))
+
+(deftest channels-and-futures
+  (define c (chan 1))
+  (send! c 5)
+  (assert-equal (recv! c) 5)
+  (assert-equal (deref (future (+ 1 2))) 3))
+
+(deftest hash-contents
+  (define h (alist->hash '((a . 1))))
+  (assert (hash? h))
+  (assert (not (hash? '((a . 1)))))
+  (assert-equal (hash-keys h) '(a)))
+
+(deftest channel-operations
+  (define c (chan 1))
+  (close! c)
+  (assert (eof-object? (recv! c)))
+  (assert-equal (select ((recv! (chan)) v v) (default 'none)) 'none)
+  (define f (future 1))
+  (deref f)
+  (assert (realized? f)))
+
+(deftest contexts
+  (define ctx (timeout-context 1000))
+  (assert (not (done? ctx)))
+  (cancel! ctx)
+  (assert (done? ctx)))
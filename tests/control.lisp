@@ -0,0 +1,77 @@
+;;; binding forms, conditionals and loops
+
+(deftest conditionals
+  (assert-equal (if #f 1) ())
+  (assert-equal (cond ((= 1 2) 'a) (else 'b)) 'b)
+  (assert-equal (case 2 ((1) 'one) ((2 3) 'few)) 'few)
+  (assert (and 1 2))
+  (assert (or () 1)))
+
+(deftest lets
+  (assert-equal (let ((a 1) (b 2)) (+ a b)) 3)
+  (assert-equal (let* ((a 1) (b (+ a 1))) b) 2)
+  (assert-equal (letrec ((ev? (lambda (n) (if (= n 0) #t (od? (- n 1)))))
+                         (od? (lambda (n) (if (= n 0) #f (ev? (- n 1))))))
+                  (ev? 10))
+                #t)
+  (assert-error (let ((a)) a)))
+
+(deftest loops
+  (assert-equal (let loop ((i 0) (acc ())) (if (= i 3) acc (loop (+ i 1) (cons i acc)))) '(2 1 0))
+  (assert-equal (do ((i 0 (+ i 1)) (acc () (cons i acc))) ((= i 3) acc)) '(2 1 0))
+  (define n 0)
+  (while (< n 5) (set! n (+ n 1)))
+  (assert-equal n 5))
+
+(deftest functions
+  (define (add &rest xs) (apply + xs))
+  (assert-equal (add 1 2 3) 6)
+  (define (greet name &key (greeting "hi")) (list greeting name))
+  (assert-equal (greet 'bob :greeting "yo") '("yo" bob)))
+
+(deftest laziness
+  (assert-equal (force (delay (+ 1 2))) 3)
+  (def nats (n) (stream-cons n (nats (+ n 1))))
+  (assert-equal (stream-take (nats 1) 3) '(1 2 3)))
+
+(deftest errors
+  (define e (make-error "boom" 'oops 1))
+  (assert-equal (error-message e) "boom")
+  (assert-equal (error-kind e) 'oops)
+  (assert-error (raise e))
+  (assert-error (error "bad" 1)))
+
+(deftest evaluation
+  (assert-equal (eval '(+ 1 2)) 3))
+
+(deftest truthiness
+  (assert-equal (boolean 0) #t)
+  (assert-equal (boolean ()) #f)
+  (assert-equal (begin 1 2 3) 3)
+  (assert-equal (begin) ()))
+
+(deftest condition-objects
+  (define e (make-error "boom" 'oops '(1 2)))
+  (assert (error? e))
+  (assert (not (error? "boom")))
+  (assert-equal (error-data e) '(1 2)))
+
+(deftest streams
+  (def nats (n) (stream-cons n (nats (+ n 1))))
+  (assert (promise? (delay 1)))
+  (assert-equal (stream-car (stream-cdr (nats 1))) 2))
+
+(deftest generators
+  (define g (generator (yield 1) (yield 2)))
+  (assert (generator? g))
+  (assert-equal (next g) 1)
+  (close-generator! g)
+  (assert (eof-object? (next g)))
+  (define h (generator (yield 'a)))
+  (assert-equal (next h) 'a)
+  (assert (eof-object? (next h))))
+
+(deftest environments
+  (def env-of (x) (the-environment))
+  (assert-equal (eval 'x (env-of 7)) 7)
+  (assert-equal (car (env-stats)) :depth))
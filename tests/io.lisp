@@ -0,0 +1,24 @@
+;;; ports, output and the environment. paths are relative to the directory
+;;; the suite is run from, the top of the repository
+
+(deftest reading-ports
+  (let ((p (open-input-file "tests/io.lisp")))
+    (assert-equal (read-char p) #\;)
+    (assert-equal (read-char p) #\;)
+    (close-port p)
+    (close-port p))
+  (assert-error (read-char 'p)))
+
+(deftest writing
+  (assert-equal (write-string "") "")
+  (assert-equal (print) ())
+  (assert (eof-object? (next (generator)))))
+
+(deftest instrumentation
+  (assert-equal (slow-call-threshold! 0) 0))
+
+(deftest pruning
+  (define kept 1)
+  (define (keeper) kept)
+  (define dropped 2)
+  (assert-equal (prune-env! 'keeper) '(dropped)))
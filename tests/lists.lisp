@@ -0,0 +1,58 @@
+;;; pairs, lists and the functions over them
+
+(deftest cons-and-accessors
+  (assert-equal (car (cons 1 2)) 1)
+  (assert-equal (cdr (cons 1 2)) 2)
+  (assert-equal (cadr '(1 2 3)) 2)
+  (assert-error (car ())))
+
+(deftest building-lists
+  (assert-equal (list 1 (+ 1 1)) '(1 2))
+  (assert-equal (append '(1 2) 3) '(1 2 3))
+  (assert-equal (length '(a b c)) 3)
+  (assert (list? ()))
+  (assert (not (list? (cons 1 2)))))
+
+(deftest mapping-and-applying
+  (assert-equal (map (lambda (x) (* x x)) '(1 2 3)) '(1 4 9))
+  (assert-equal (map + '(1 2) '(10 20)) '(11 22))
+  (assert-equal (apply + 1 '(2 3)) 6))
+
+(deftest mutation
+  (define p (list 1 2))
+  (set-car! p 0)
+  (set-cdr! (cdr p) '(3))
+  (assert-equal p '(0 2 3)))
+
+(deftest alists
+  (define al '((a . 1) (b . 2)))
+  (assert-equal (assq 'b al) '(b . 2))
+  (assert-equal (alist-get 'c al 0) 0)
+  (assert-equal (acons 'c 3 ()) '((c . 3))))
+
+(deftest walking-trees
+  (assert-equal (diff '(1 (2 3)) '(1 (2 4))) '(((1 1) 3 4)))
+  (assert-equal (sexp-select '(cfg (port 80) (port 81)) '(port)) '((port 80) (port 81)))
+  (assert-equal (prewalk (lambda (x) (if (num? x) (+ x 1) x)) '(1 (2))) '(2 (3))))
+
+(deftest quasiquote-templates
+  (define xs '(2 3))
+  (assert-equal `(1 ,@xs ,(+ 2 2)) '(1 2 3 4)))
+
+(deftest quoting
+  (assert-equal (quote (a b)) '(a b))
+  (assert-equal (quasiquote (1 (unquote (+ 1 1)) (unquote-splicing (list 3 4)))) '(1 2 3 4))
+  (assert-error (unquote 1)))
+
+(deftest more-walking
+  (assert-equal (postwalk (lambda (x) (if (num? x) (* x 2) x)) '(1 (2))) '(2 (4)))
+  (assert-equal (assoc "b" '(("a" . 1) ("b" . 2))) '("b" . 2))
+  (assert (pair? '(1)))
+  (assert (not (pair? ()))))
+
+(deftest call-graphs
+  (def sq (x) (* x x))
+  (def quad (x) (sq (sq x)))
+  (assert-equal (uses 'quad) '(sq))
+  (assert-equal (used-by 'sq) '(quad))
+  (assert-equal (dot-graph '((a b))) "digraph {\n  \"a\" -> \"b\";\n}\n"))
@@ -0,0 +1,33 @@
+;;; the numeric tower and formatting
+
+(deftest arithmetic
+  (assert-equal (+ 1 2 3) 6)
+  (assert-equal (- 10 4 1) 5)
+  (assert-equal (* 2 3) 6)
+  (assert-equal (/ 1 3) 1/3)
+  (assert-error (/ 1 0)))
+
+(deftest comparisons
+  (assert (< 1 2 3))
+  (assert (not (< 1 3 2)))
+  (assert (= 1 1.0))
+  (assert-equal (compare 2 1) 1))
+
+(deftest exactness
+  (assert (integer? 100000000000000000000))
+  (assert (rational? 1/2))
+  (assert (float? 1.5))
+  (assert (fixnum? 1)))
+
+(deftest decimals
+  (assert-equal (decimal-round #d2.345 2) #d2.34)
+  (assert (decimal? (decimal 1/8 3))))
+
+(deftest formatting
+  (assert-equal (format-number 1234567.891 :precision 2) "1,234,567.89"))
+
+(deftest orderings
+  (assert (> 3 2 1))
+  (assert (>= 2 2 1))
+  (assert (<= 1 1 2))
+  (assert (not (> 1 2))))
@@ -0,0 +1,35 @@
+;;; strings, characters, symbols and regular expressions
+
+(deftest string-functions
+  (assert-equal (string-length "héllo") 5)
+  (assert-equal (substring "héllo" 1 3) "él")
+  (assert-equal (string-upcase "abc") "ABC")
+  (assert-equal (string-byte-length "é") 2)
+  (assert-error (substring "abc" 2 9)))
+
+(deftest characters
+  (assert-equal (char->int #\a) 97)
+  (assert-equal (int->char 98) #\b))
+
+(deftest symbols
+  (assert (eq? (string->symbol "abc") 'abc))
+  (assert-equal (symbol->string 'abc) "abc"))
+
+(deftest regular-expressions
+  (assert (re-match? (re-compile "^a+$") "aaa"))
+  (assert-equal (re-find "[0-9]+" "ab12cd") "12")
+  (assert-equal (re-find-all "[0-9]" "a1b2") '("1" "2"))
+  (assert-equal (re-replace "[0-9]" "a1b2" "#") "a#b#"))
+
+(deftest more-string-functions
+  (assert-equal (string-ref "héllo" 1) #\é)
+  (assert-equal (string-downcase "ABC") "abc")
+  (assert-equal (string-byte-ref "é" 0) 195)
+  (assert-equal (byte-substring "héllo" 0 3) "hé")
+  (assert-error (string-ref "é" 1)))
+
+(deftest symbol-plists
+  (assert-equal (put 'widget :size 3) 3)
+  (assert-equal (get 'widget :size) 3)
+  (assert-equal (get 'widget :color) ())
+  (assert-equal (symbol-plist 'widget) '(:size 3)))
@@ -0,0 +1,19 @@
+;;; times and dates
+
+(deftest converting-times
+  (define t1 (string->time "2026-10-16T09:30:00Z"))
+  (assert-equal (time->string (time-add t1 90)) "2026-10-16T09:31:30Z")
+  (assert-equal (time->string t1 "2006-01-02") "2026-10-16")
+  (assert-equal (time-diff (time-add t1 1.5) t1) 1.5)
+  (assert-equal (time-parts t1) '(:year 2026 :month 10 :day 16 :hour 9 :minute 30 :second 0 :weekday 5)))
+
+(deftest formatting-dates
+  (define t1 (string->time "2026-10-16T22:30:00Z"))
+  (assert-equal (format-date t1 :long) "Friday, October 16, 2026")
+  (assert-equal (format-date t1 :long "Pacific/Auckland") "Saturday, October 17, 2026")
+  (assert (= (time-diff (parse-date "2026-10-17 00:30:00" :datetime "Europe/Paris") t1) 0))
+  (assert-error (parse-date "x" :date)))
+
+(deftest the-clock
+  (define before (now))
+  (assert (>= (time-diff (now) before) 0)))
@@ -0,0 +1,20 @@
+;;; the type predicates
+
+(deftest atoms
+  (assert (nil? ()))
+  (assert (not (nil? 0)))
+  (assert (symbol? 'a))
+  (assert (not (symbol? "a")))
+  (assert (string? "a"))
+  (assert (char? #\a))
+  (assert (not (char? "a")))
+  (assert (boolean? #f))
+  (assert (not (boolean? ())))
+  (assert (keyword? :a))
+  (assert (not (keyword? 'a))))
+
+(deftest procedures
+  (assert (lambda? (lambda (x) x)))
+  (assert (not (lambda? car)))
+  (assert (intrinsic? car))
+  (assert (not (intrinsic? 'car))))
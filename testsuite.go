@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// a test defined with deftest, run in the environment of the file defining it
+type lispTest struct {
+	file string
+	name string
+	body LispObject
+	env  Environment
+}
+
+// collects the tests in a directory of .lisp files and runs them, for
+// --test. the files see deftest and the assertions on top of the usual
+// global environment
+type testSuite struct {
+	file  string
+	tests []lispTest
+}
+
+func (s *testSuite) env() Environment {
+	env := newGlobalEnv()
+	env.Put("deftest", Intrinsic{op: s.deftest})
+	env.Put("assert", Intrinsic{op: assertTrue})
+	env.Put("assert-equal", Intrinsic{op: assertEqual})
+	env.Put("assert-error", Intrinsic{op: assertError})
+	return env
+}
+
+// (deftest adds-up (assert-equal (+ 1 2) 3)) -> adds-up
+func (s *testSuite) deftest(rawlist []LispObject, env Environment) LispObject {
+	name, ok := rawlist[1].(*symbol)
+	if !ok {
+		panic("deftest needs a name, got " + rawlist[1].Print())
+	}
+	s.tests = append(s.tests, lispTest{file: s.file, name: name.name, body: body(rawlist[2:]), env: env})
+	return name
+}
+
+func testFailure(msg string) *lispError {
+	return &lispError{message: msg, kind: intern("test-failure"), data: Nil}
+}
+
+// (assert (< 1 2)) fails the test when its argument is false
+func assertTrue(rawlist []LispObject, env Environment) LispObject {
+	if !lispToBool(rawlist[1].Eval(env)) {
+		panic(testFailure("assertion failed: " + rawlist[1].Print()))
+	}
+	return True
+}
+
+// (assert-equal (+ 1 2) 3) fails the test unless the values are equal?. when
+// they're lists the message points at each element that differs
+func assertEqual(rawlist []LispObject, env Environment) LispObject {
+	got := rawlist[1].Eval(env)
+	want := rawlist[2].Eval(env)
+	if equalHelper(got, want) {
+		return True
+	}
+	msg := fmt.Sprintf("%v gave %v, expected %v", rawlist[1].Print(), got.Print(), want.Print())
+	sep := ": "
+	for _, d := range diffHelper(nil, got, want) {
+		entry := toSlice(d)
+		if entry[0] != Nil {
+			msg += fmt.Sprintf("%vat %v got %v, expected %v", sep, entry[0].Print(), entry[1].Print(), entry[2].Print())
+			sep = "; "
+		}
+	}
+	panic(testFailure(msg))
+}
+
+// (assert-error (car 1)) fails the test unless evaluating its argument does
+func assertError(rawlist []LispObject, env Environment) LispObject {
+	if protect(func() { rawlist[1].Eval(env) }) == nil {
+		panic(testFailure("expected an error from " + rawlist[1].Print()))
+	}
+	return True
+}
+
+// loads every .lisp file in dir, in name order, then runs the tests they
+// define, writing a line for each failure and a summary to out. the error
+// says how many failed
+func runTestSuite(dir string, out io.Writer) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.lisp"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	s := &testSuite{}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		s.file = filepath.Base(path)
+		if _, err := evalString(string(src), s.env()); err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+	}
+	failures := s.run()
+	for _, failure := range failures {
+		fmt.Fprintf(out, "FAIL %v\n", failure)
+	}
+	failed := len(failures)
+	fmt.Fprintf(out, "%d passed, %d failed\n", len(s.tests)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(s.tests))
+	}
+	return nil
+}
+
+// runs every test defined so far, returning a message for each that fails
+func (s *testSuite) run() []string {
+	failures := []string{}
+	for _, t := range s.tests {
+		if err := protect(func() { t.body.Eval(t.env) }); err != nil {
+			failures = append(failures, fmt.Sprintf("%v: %v: %v", t.file, t.name, err))
+		}
+	}
+	return failures
+}
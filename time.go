@@ -0,0 +1,137 @@
+package main
+
+import (
+	"time"
+	// zone names work even where the system has no zoneinfo
+	_ "time/tzdata"
+)
+
+type lispTime struct {
+	t time.Time
+}
+
+func (t lispTime) Eval(env Environment) LispObject {
+	return t
+}
+func (t lispTime) Print() string {
+	return "<time " + t.t.Format(time.RFC3339Nano) + ">"
+}
+
+func checkTime(obj LispObject) time.Time {
+	t, ok := obj.(lispTime)
+	if !ok {
+		panic("expected a time, got " + obj.Print())
+	}
+	return t.t
+}
+
+// layouts that can be named with a keyword instead of spelled out
+var namedLayouts = map[keyword]string{
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+	"date":     "2006-01-02",
+	"time":     "15:04:05",
+	"datetime": "2006-01-02 15:04:05",
+	"kitchen":  time.Kitchen,
+	"long":     "Monday, January 2, 2006"}
+
+func checkLayout(obj LispObject) string {
+	switch l := obj.(type) {
+	case lispString:
+		return string(l)
+	case keyword:
+		if layout, ok := namedLayouts[l]; ok {
+			return layout
+		}
+	}
+	panic("expected a layout string or name, got " + obj.Print())
+}
+
+// layouts use Go's reference time, defaulting to RFC 3339
+func optionalLayout(rawlist []LispObject, i int, env Environment) string {
+	if len(rawlist) > i {
+		return checkLayout(rawlist[i].Eval(env))
+	}
+	return time.RFC3339
+}
+
+// an optional zone name like "Europe/Paris" at rawlist[i], defaulting to UTC
+func optionalZone(rawlist []LispObject, i int, env Environment) *time.Location {
+	if len(rawlist) <= i {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(string(rawlist[i].Eval(env).(lispString)))
+	if err != nil {
+		panic(err.Error())
+	}
+	return loc
+}
+
+// (now) -> <time 2026-10-16T09:30:00Z>
+func now(rawlist []LispObject, env Environment) LispObject {
+	return lispTime{t: time.Now()}
+}
+
+// (time->string t "2006-01-02") -> "2026-10-16"
+func timeToString(rawlist []LispObject, env Environment) LispObject {
+	t := checkTime(rawlist[1].Eval(env))
+	return lispString(t.Format(optionalLayout(rawlist, 2, env)))
+}
+
+// (string->time "2026-10-16" "2006-01-02") -> <time 2026-10-16T00:00:00Z>
+func stringToTime(rawlist []LispObject, env Environment) LispObject {
+	s := rawlist[1].Eval(env).(lispString)
+	t, err := time.Parse(optionalLayout(rawlist, 2, env), string(s))
+	if err != nil {
+		panic(err.Error())
+	}
+	return lispTime{t: t}
+}
+
+// (time-add t 90) -> t plus 90 seconds, which may be fractional or negative
+func timeAdd(rawlist []LispObject, env Environment) LispObject {
+	t := checkTime(rawlist[1].Eval(env))
+	seconds := toFlonum(rawlist[2].Eval(env))
+	return lispTime{t: t.Add(time.Duration(float64(seconds) * float64(time.Second)))}
+}
+
+// (time-diff a b) -> seconds from b to a
+func timeDiff(rawlist []LispObject, env Environment) LispObject {
+	a := checkTime(rawlist[1].Eval(env))
+	b := checkTime(rawlist[2].Eval(env))
+	return flonum(a.Sub(b).Seconds())
+}
+
+// (time-parts t) -> (:year 2026 :month 10 :day 16 :hour 9 :minute 30
+// :second 0 :weekday 5), weekdays counting from sunday as 0
+func timeParts(rawlist []LispObject, env Environment) LispObject {
+	t := checkTime(rawlist[1].Eval(env))
+	return list(
+		keyword("year"), fixnum(t.Year()),
+		keyword("month"), fixnum(t.Month()),
+		keyword("day"), fixnum(t.Day()),
+		keyword("hour"), fixnum(t.Hour()),
+		keyword("minute"), fixnum(t.Minute()),
+		keyword("second"), fixnum(t.Second()),
+		keyword("weekday"), fixnum(t.Weekday()))
+}
+
+// (format-date t :long "Pacific/Auckland") -> "Saturday, October 17, 2026",
+// showing t in the zone, or UTC without one
+func formatDate(rawlist []LispObject, env Environment) LispObject {
+	t := checkTime(rawlist[1].Eval(env))
+	layout := checkLayout(rawlist[2].Eval(env))
+	return lispString(t.In(optionalZone(rawlist, 3, env)).Format(layout))
+}
+
+// (parse-date "2026-10-16 09:30:00" :datetime "Europe/Paris") reads a time
+// with no offset of its own as being in the zone, or UTC without one
+func parseDate(rawlist []LispObject, env Environment) LispObject {
+	s := rawlist[1].Eval(env).(lispString)
+	layout := checkLayout(rawlist[2].Eval(env))
+	t, err := time.ParseInLocation(layout, string(s), optionalZone(rawlist, 3, env))
+	if err != nil {
+		panic(err.Error())
+	}
+	return lispTime{t: t}
+}
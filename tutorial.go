@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// the lessons --tutorial walks through, in file name order. each file holds
+// one (lesson title intro (step prompt answer hint) ...) form, read as data
+//
+//go:embed lessons/*.lisp
+var lessonFiles embed.FS
+
+// one exercise: the prompt shown, the value a correct expression evaluates
+// to, compared with equal?, and an expression that gets there
+type tutorialStep struct {
+	prompt string
+	want   LispObject
+	hint   string
+}
+
+type lesson struct {
+	title string
+	intro string
+	steps []tutorialStep
+}
+
+func checkLessonString(obj LispObject, what string) string {
+	s, ok := obj.(lispString)
+	if !ok {
+		panic("a lesson's " + what + " must be a string, got " + obj.Print())
+	}
+	return string(s)
+}
+
+func parseLesson(form LispObject) lesson {
+	l, ok := listToSlice(form)
+	if !ok || len(l) < 3 || l[0] != intern("lesson") {
+		panic("expected (lesson title intro step ...), got " + form.Print())
+	}
+	les := lesson{title: checkLessonString(l[1], "title"), intro: checkLessonString(l[2], "intro")}
+	for i, obj := range l[3:] {
+		step, ok := listToSlice(obj)
+		if !ok || len(step) != 4 || step[0] != intern("step") {
+			panic(fmt.Sprintf("%v step %d is not a (step prompt answer hint) list", les.title, i+1))
+		}
+		les.steps = append(les.steps, tutorialStep{
+			prompt: checkLessonString(step[1], "prompt"),
+			want:   step[2],
+			hint:   checkLessonString(step[3], "hint")})
+	}
+	return les
+}
+
+// every embedded lesson, in order
+func loadLessons() (lessons []lesson, err error) {
+	entries, err := lessonFiles.ReadDir("lessons")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		src, err := lessonFiles.ReadFile("lessons/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		err = protect(func() {
+			for _, form := range ReadAll(string(src)) {
+				lessons = append(lessons, parseLesson(form))
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lessons, nil
+}
+
+// reads one complete expression's worth of lines, returning false at the end
+// of input
+func readAnswer(in *bufio.Reader) (string, bool) {
+	line, err := in.ReadString('\n')
+	for err == nil && incomplete(line) {
+		var more string
+		more, err = in.ReadString('\n')
+		line += more
+	}
+	if err != nil && strings.TrimSpace(line) == "" {
+		return "", false
+	}
+	return line, true
+}
+
+// runs the lessons, prompting on out for expressions read from in until
+// each step's answer is right. :skip gives up on a step and :quit stops
+func runTutorial(in io.Reader, out io.Writer) error {
+	lessons, err := loadLessons()
+	if err != nil {
+		return err
+	}
+	env := newGlobalEnv()
+	answers := bufio.NewReader(in)
+	fmt.Fprint(out, "type :skip to see an answer and move on, or :quit to stop\n\n")
+	for _, les := range lessons {
+		fmt.Fprintf(out, "== %v ==\n%v\n", les.title, les.intro)
+		for _, step := range les.steps {
+			fmt.Fprintf(out, "\n%v\n", step.prompt)
+			for {
+				fmt.Fprint(out, "tutorial> ")
+				answer, ok := readAnswer(answers)
+				if !ok || strings.TrimSpace(answer) == ":quit" {
+					fmt.Fprintln(out)
+					return nil
+				}
+				if strings.TrimSpace(answer) == ":skip" {
+					evalString(step.hint, env)
+					fmt.Fprintf(out, "one answer is %v\n", step.hint)
+					break
+				}
+				if strings.TrimSpace(answer) == "" {
+					continue
+				}
+				result, err := evalString(answer, env)
+				if err != nil {
+					fmt.Fprintf(out, "error: %v\n", err)
+				} else if equalHelper(result, step.want) {
+					fmt.Fprintln(out, "right!")
+					break
+				} else {
+					fmt.Fprintf(out, "that gives %v, not %v. try %v\n", result.Print(), step.want.Print(), step.hint)
+				}
+			}
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintln(out, "that's every lesson!")
+	return nil
+}